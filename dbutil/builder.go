@@ -0,0 +1,192 @@
+package dbutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// paramPrefix is the prefix of names of parameters bound by Builder.
+const paramPrefix = "p"
+
+// Builder builds a query from constant fragments, quoted identifiers and bound parameters,
+// so that the query is accepted by the helpers without building it from strings at runtime.
+//
+//	q, args, err := dbutil.NewBuilder(db.DriverName()).
+//		Append("SELECT id, name FROM users").
+//		Where(dbutil.Cond("status = ?", status), dbutil.CondIf(name != "", "name = ?", name)).
+//		OrderBy(sortColumn, desc).
+//		Build()
+type Builder struct {
+	dialect Dialect
+	buf     strings.Builder
+	args    map[string]any
+	err     error
+}
+
+// Condition is a condition of WHERE clause.
+type Condition struct {
+	fragment Query
+	args     []any
+	skip     bool
+}
+
+// Cond returns a condition whose "?" are bound to the args in order.
+// A slice arg is expanded to parameters separated by commas. e.g. "id IN (?)"
+// "??" is a literal "?". e.g. "tags ?? ?" for the jsonb operator of PostgreSQL
+func Cond(fragment Query, args ...any) Condition {
+	return Condition{fragment: fragment, args: args}
+}
+
+// CondIf returns a condition which is used only if ok is true. (e.g. optional filters)
+func CondIf(ok bool, fragment Query, args ...any) Condition {
+	return Condition{fragment: fragment, args: args, skip: !ok}
+}
+
+// NewBuilder returns Builder which quotes identifiers by Dialect of the driver such as db.DriverName().
+func NewBuilder(driver string) *Builder {
+	d, err := DialectOf(driver)
+	return &Builder{dialect: d, args: make(map[string]any), err: err}
+}
+
+// Append appends the fragment whose "?" are bound to the args in order.
+// A slice arg is expanded to parameters separated by commas.
+// "??" is a literal "?" such as jsonb operators of PostgreSQL and "?" in string literals.
+func (b *Builder) Append(fragment Query, args ...any) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	s := string(fragment)
+	if n := strings.Count(strings.ReplaceAll(s, "??", ""), "?"); n != len(args) {
+		b.err = fmt.Errorf("fragment %q has %d placeholders but %d args", fragment, n, len(args))
+		return b
+	}
+
+	for i := 0; ; {
+		j := strings.IndexByte(s, '?')
+		if j < 0 {
+			b.buf.WriteString(s)
+			return b
+		}
+		b.buf.WriteString(s[:j])
+		if strings.HasPrefix(s[j:], "??") {
+			b.buf.WriteByte('?')
+			s = s[j+2:]
+			continue
+		}
+		b.bind(args[i])
+		i++
+		s = s[j+1:]
+	}
+}
+
+// Ident appends the identifier quoted for the driver. Dots separate qualifiers. e.g. "u.name"
+func (b *Builder) Ident(name string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	quoted, err := b.dialect.QuoteIdent(name)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.buf.WriteString(quoted)
+	return b
+}
+
+// Where appends WHERE clause of the conditions joined by AND.
+// Conditions of CondIf whose ok is false are skipped, and nothing is appended if all are skipped.
+func (b *Builder) Where(conds ...Condition) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	first := true
+	for _, c := range conds {
+		if c.skip {
+			continue
+		}
+		if first {
+			b.Append(" WHERE ")
+			first = false
+		} else {
+			b.Append(" AND ")
+		}
+		b.Append("(").Append(c.fragment, c.args...).Append(")")
+	}
+	return b
+}
+
+// OrderBy appends ORDER BY clause of the quoted column.
+// Check the column with the allowed list if it comes from users, because any columns can be sorted.
+func (b *Builder) OrderBy(column string, desc bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.Append(" ORDER BY ").Ident(column)
+	if desc {
+		b.Append(" DESC")
+	}
+	return b
+}
+
+// Now appends the expression of the current date and time of the driver.
+func (b *Builder) Now() *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.buf.WriteString(b.dialect.CurrentTimestamp())
+	return b
+}
+
+// Limit appends LIMIT and OFFSET clause of the driver. limit <= 0 means no limit.
+func (b *Builder) Limit(limit, offset int) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if clause := b.dialect.LimitOffset(limit, offset); clause != "" {
+		b.buf.WriteString(" " + clause)
+	}
+	return b
+}
+
+// Build returns the query and the args for the helpers.
+func (b *Builder) Build() (Query, map[string]any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return Query(b.buf.String()), b.args, nil
+}
+
+// bind appends named parameters of the arg.
+func (b *Builder) bind(arg any) {
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		b.buf.WriteString(":" + b.param(arg))
+		return
+	}
+
+	if v.Len() == 0 {
+		b.err = errors.New("slice arg is empty")
+		return
+	}
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b.buf.WriteString(", ")
+		}
+		b.buf.WriteString(":" + b.param(v.Index(i).Interface()))
+	}
+}
+
+// param adds the arg and returns its name.
+func (b *Builder) param(arg any) string {
+	name := fmt.Sprintf("%s%d", paramPrefix, len(b.args)+1)
+	b.args[name] = arg
+	return name
+}
@@ -0,0 +1,158 @@
+package dbutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		build    func(b *dbutil.Builder) *dbutil.Builder
+		driver   string
+		want     string
+		wantArgs map[string]any
+		wantErr  bool
+	}{
+		"filters": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("SELECT id FROM users").
+					Where(dbutil.Cond("status = ?", active), dbutil.CondIf(false, "name = ?", "skipped"), dbutil.CondIf(true, "id IN (?)", []int{1, 2}))
+			},
+			mysqlDriver,
+			"SELECT id FROM users WHERE (status = :p1) AND (id IN (:p2, :p3))",
+			map[string]any{"p1": active, "p2": 1, "p3": 2},
+			false,
+		},
+		"no filters": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("SELECT id FROM users").Where(dbutil.CondIf(false, "name = ?", "skipped"))
+			},
+			pgsqlDriver,
+			"SELECT id FROM users",
+			map[string]any{},
+			false,
+		},
+		"mysql order by": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("SELECT id FROM users u").OrderBy("u.created_at`; DROP TABLE users; --", true)
+			},
+			mysqlDriver,
+			"SELECT id FROM users u ORDER BY `u`.`created_at``; DROP TABLE users; --` DESC",
+			map[string]any{},
+			false,
+		},
+		"pgsql ident": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("SELECT ").Ident(`na"me`).Append(" FROM users")
+			},
+			pgsqlDriver,
+			`SELECT "na""me" FROM users`,
+			map[string]any{},
+			false,
+		},
+		"now": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("UPDATE users SET status = ?, updated_at = ", active).Now().Where(dbutil.Cond("id = ?", 1))
+			},
+			pgsqlDriver,
+			"UPDATE users SET status = :p1, updated_at = NOW() WHERE (id = :p2)",
+			map[string]any{"p1": active, "p2": 1},
+			false,
+		},
+		"bytes": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("SELECT id FROM users WHERE email = ?", []byte("a"))
+			},
+			sqliteDriver,
+			"SELECT id FROM users WHERE email = :p1",
+			map[string]any{"p1": []byte("a")},
+			false,
+		},
+		"escaped": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("SELECT id FROM docs").Where(dbutil.Cond("tags ?? ?", "a"), dbutil.Cond("note <> 'why??'"))
+			},
+			pgsqlDriver,
+			"SELECT id FROM docs WHERE (tags ? :p1) AND (note <> 'why?')",
+			map[string]any{"p1": "a"},
+			false,
+		},
+		"escaped mismatch": {
+			func(b *dbutil.Builder) *dbutil.Builder { return b.Append("SELECT id FROM docs WHERE tags ??| ?") },
+			pgsqlDriver, "", nil, true,
+		},
+		"error before where": {
+			func(b *dbutil.Builder) *dbutil.Builder {
+				return b.Append("SELECT id FROM users WHERE id = ?").Where(dbutil.Cond("status = ?", active)).OrderBy("id", false)
+			},
+			mysqlDriver, "", nil, true,
+		},
+		"args mismatch": {
+			func(b *dbutil.Builder) *dbutil.Builder { return b.Append("SELECT id FROM users WHERE id = ?") },
+			mysqlDriver, "", nil, true,
+		},
+		"empty slice": {
+			func(b *dbutil.Builder) *dbutil.Builder { return b.Append("SELECT id FROM users WHERE id IN (?)", []int{}) },
+			mysqlDriver, "", nil, true,
+		},
+		"empty ident": {
+			func(b *dbutil.Builder) *dbutil.Builder { return b.Append("SELECT id FROM users").OrderBy("u.", false) },
+			mysqlDriver, "", nil, true,
+		},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, args, err := tt.build(dbutil.NewBuilder(tt.driver)).Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error want: %t, got: %v", tt.wantErr, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("want: %s, got: %s", tt.want, got)
+			}
+			if diff := cmp.Diff(tt.wantArgs, args); diff != "" {
+				t.Errorf("args (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuilderHelpers(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "builder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	name := "builder"
+	query, args, err := dbutil.NewBuilder(db.DriverName()).
+		Append("SELECT id, name, status, created_at, updated_at FROM users").
+		Where(dbutil.Cond("id IN (?)", []int{1, 2}), dbutil.CondIf(name != "", "name = ?", name)).
+		OrderBy("id", true).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := dbutil.SelectContext[User](ctx, db, query, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != name {
+		t.Errorf("want: a user of %s, got: %v", name, list)
+	}
+}
@@ -0,0 +1,264 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/multierr"
+)
+
+const defaultProbeInterval = 5 * time.Second
+
+// Balancer is a policy to choose a replica.
+type Balancer int
+
+const (
+	RoundRobin       Balancer = iota // chooses replicas in turn
+	LeastConnections                 // chooses a replica which has the fewest connections in use
+)
+
+// ClusterOptions are options of Cluster.
+// Zero values are replaced with defaults.
+type ClusterOptions struct {
+	Balancer      Balancer      // RoundRobin by default
+	ProbeInterval time.Duration // interval to ping replicas, 5s by default
+}
+
+// primaryKey is a context key to read from the primary.
+type primaryKey struct{}
+
+// WithPrimary returns a context which makes Cluster read from the primary.
+// Use it to read what has just been written. (read-after-write consistency)
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// usePrimary reports whether the context requires reading from the primary.
+func usePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+var _ sqlx.ExtContext = (*Cluster)(nil)
+
+// Cluster is DB handle which has a primary and replicas.
+// Reads go to a healthy replica and writes and transactions go to the primary.
+// It reads from the primary if there are no healthy replicas.
+type Cluster struct {
+	primary  *sqlx.DB
+	replicas []*replica
+	balancer Balancer
+	next     atomic.Uint64
+	done     chan struct{}
+	wg       sync.WaitGroup
+	closed   sync.Once
+}
+
+// replica is DB handle of a replica and its health.
+type replica struct {
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+// OpenClusterContext returns Cluster of the primary and replicas.
+// It fails if the primary can not be connected, but unhealthy replicas are just ejected until they recover.
+func OpenClusterContext(ctx context.Context, primary *Config, replicas []*Config, opts *ClusterOptions) (*Cluster, error) {
+	var o ClusterOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.ProbeInterval <= 0 {
+		o.ProbeInterval = defaultProbeInterval
+	}
+
+	db, err := OpenContext(ctx, primary)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cluster{primary: db, balancer: o.Balancer, done: make(chan struct{})}
+	for _, cfg := range replicas {
+		rdb, err := open(cfg)
+		if err != nil {
+			return nil, multierr.Append(err, c.closeAll())
+		}
+		c.replicas = append(c.replicas, &replica{db: rdb})
+	}
+
+	// The first probe is bounded as well as the following ones even if ctx has no deadline.
+	probeCtx, cancel := context.WithTimeout(ctx, o.ProbeInterval)
+	c.probe(probeCtx)
+	cancel()
+	c.wg.Add(1)
+	go c.probeLoop(o.ProbeInterval)
+
+	return c, nil
+}
+
+// Primary returns DB handle of the primary.
+func (c *Cluster) Primary() *sqlx.DB {
+	return c.primary
+}
+
+// Reader returns DB handle to read on the context.
+func (c *Cluster) Reader(ctx context.Context) *sqlx.DB {
+	if usePrimary(ctx) {
+		return c.primary
+	}
+	if r := c.choose(); r != nil {
+		return r.db
+	}
+	return c.primary
+}
+
+// choose returns a healthy replica by the balancer, or nil if there are no healthy replicas.
+func (c *Cluster) choose() *replica {
+	var healthy []*replica
+	for _, r := range c.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if c.balancer == LeastConnections {
+		least := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.db.Stats().InUse < least.db.Stats().InUse {
+				least = r
+			}
+		}
+		return least
+	}
+
+	return healthy[(c.next.Add(1)-1)%uint64(len(healthy))]
+}
+
+// probeLoop pings replicas at intervals until Cluster is closed.
+func (c *Cluster) probeLoop(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			c.probe(ctx)
+			cancel()
+		}
+	}
+}
+
+// probe pings replicas, and ejects unhealthy ones or restores recovered ones.
+func (c *Cluster) probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range c.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			r.healthy.Store(r.db.PingContext(ctx) == nil)
+		}(r)
+	}
+	wg.Wait()
+}
+
+// eject ejects the replica if err means that its connection is broken.
+func (c *Cluster) eject(db *sqlx.DB, err error) {
+	var netErr net.Error
+	if !errors.Is(err, driver.ErrBadConn) && !errors.As(err, &netErr) {
+		return
+	}
+
+	for _, r := range c.replicas {
+		if r.db == db {
+			r.healthy.Store(false)
+		}
+	}
+}
+
+// QueryContext runs a query on a replica.
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	db := c.Reader(ctx)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		c.eject(db, err)
+	}
+	return rows, err
+}
+
+// QueryxContext runs a query on a replica.
+func (c *Cluster) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	db := c.Reader(ctx)
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		c.eject(db, err)
+	}
+	return rows, err
+}
+
+// QueryRowxContext runs a query which returns a row on a replica.
+func (c *Cluster) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	db := c.Reader(ctx)
+	row := db.QueryRowxContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		c.eject(db, err)
+	}
+	return row
+}
+
+// ExecContext runs a query on the primary.
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.primary.ExecContext(ctx, query, args...)
+}
+
+// BeginTxx starts a transaction on the primary.
+func (c *Cluster) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	return c.primary.BeginTxx(ctx, opts)
+}
+
+// DriverName returns the driver name of the primary.
+func (c *Cluster) DriverName() string {
+	return c.primary.DriverName()
+}
+
+// Rebind transforms a query for the bind type of the primary.
+func (c *Cluster) Rebind(query string) string {
+	return c.primary.Rebind(query)
+}
+
+// BindNamed binds a query which has named parameters for the bind type of the primary.
+func (c *Cluster) BindNamed(query string, arg any) (string, []any, error) {
+	return c.primary.BindNamed(query, arg)
+}
+
+// Close stops probing replicas and closes all DB handles.
+// It can be called more than once.
+func (c *Cluster) Close() error {
+	c.closed.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+	})
+	return c.closeAll()
+}
+
+// closeAll closes all DB handles.
+func (c *Cluster) closeAll() error {
+	err := c.primary.Close()
+	for _, r := range c.replicas {
+		err = multierr.Append(err, r.db.Close())
+	}
+	return err
+}
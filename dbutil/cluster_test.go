@@ -0,0 +1,224 @@
+package dbutil_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/jmoiron/sqlx"
+)
+
+const queryInsertName = `INSERT INTO users (id, name, email, status) VALUES (1, :name, '', 1);`
+
+// openSQLite returns DB config of a new SQLite file which has a user of the name.
+func openSQLite(ctx context.Context, t *testing.T, name string) *dbutil.Config {
+	t.Helper()
+
+	cfg, err := dbutil.ParseDSN("sqlite://" + filepath.Join(t.TempDir(), name+".sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := dbutil.OpenContext(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createTable(ctx, t, db)
+	if _, err := sqlx.NamedExecContext(ctx, db, queryInsertName, map[string]any{"name": name}); err != nil {
+		t.Fatal(err)
+	}
+
+	return cfg
+}
+
+func TestCluster(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	primary := openSQLite(ctx, t, "primary")
+	replicas := []*dbutil.Config{openSQLite(ctx, t, "replica1"), openSQLite(ctx, t, "replica2")}
+
+	cases := map[string]struct {
+		balancer dbutil.Balancer
+	}{
+		"round robin":       {dbutil.RoundRobin},
+		"least connections": {dbutil.LeastConnections},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+			defer cancel()
+
+			c, err := dbutil.OpenClusterContext(ctx, primary, replicas, &dbutil.ClusterOptions{Balancer: tt.balancer})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			args := map[string]any{"id": 1, "status": active}
+			got := make(map[string]bool)
+			for i := 0; i < 4; i++ {
+				u, err := dbutil.GetContext[User](ctx, c, querySelect, args)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[u.Name] = true
+			}
+			if got["primary"] || !got["replica1"] {
+				t.Errorf("reads want: replicas, got: %v", got)
+			}
+			if tt.balancer == dbutil.RoundRobin && !got["replica2"] {
+				t.Errorf("reads want: all replicas in turn, got: %v", got)
+			}
+
+			u, err := dbutil.GetContext[User](dbutil.WithPrimary(ctx), c, querySelect, args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Name != "primary" {
+				t.Errorf("read with WithPrimary want: primary, got: %s", u.Name)
+			}
+		})
+	}
+}
+
+func TestClusterWrite(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	c, err := dbutil.OpenClusterContext(ctx, openSQLite(ctx, t, "primary"), []*dbutil.Config{openSQLite(ctx, t, "replica")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := sqlx.NamedExecContext(ctx, c, queryUpdate, map[string]any{"id": 1, "beforeSts": active, "afterSts": non}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := c.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := dbutil.SelectTxContext[User](ctx, tx, querySelect, map[string]any{"id": 1, "status": non})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "primary" {
+		t.Errorf("transaction want: the updated user of primary, got: %v", list)
+	}
+
+	// The replica has not been updated.
+	list, err = dbutil.SelectContext[User](ctx, c, querySelect, map[string]any{"id": 1, "status": active})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "replica" {
+		t.Errorf("read want: the user of replica, got: %v", list)
+	}
+
+	// Close can be called more than once.
+	for i := 0; i < 2; i++ {
+		if err := c.Close(); err != nil {
+			t.Errorf("close %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestClusterEject(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	// The directory of the replica does not exist.
+	broken, err := dbutil.ParseDSN("sqlite://" + filepath.Join(t.TempDir(), dummy, "replica.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := dbutil.OpenClusterContext(ctx, openSQLite(ctx, t, "primary"), []*dbutil.Config{broken}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Reader(ctx) != c.Primary() {
+		t.Error("reader want: the primary without healthy replicas, got: the replica")
+	}
+}
+
+func TestClusterReprobe(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	// The directory of the second replica is created after opening Cluster.
+	dir := filepath.Join(t.TempDir(), dummy)
+	late, err := dbutil.ParseDSN("sqlite://" + filepath.Join(dir, "replica2.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replicas := []*dbutil.Config{openSQLite(ctx, t, "replica1"), late}
+	c, err := dbutil.OpenClusterContext(ctx, openSQLite(ctx, t, "primary"), replicas, &dbutil.ClusterOptions{ProbeInterval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	replica := c.Reader(ctx)
+	if replica == c.Primary() {
+		t.Fatal("reader want: the healthy replica, got: the primary")
+	}
+
+	// Errors which do not break the connection do not eject the replica.
+	dbutil.ExportEject(c, replica, errors.New(dummy))
+	if c.Reader(ctx) != replica {
+		t.Error("reader want: the replica after a query error, got: another one")
+	}
+
+	// The replica is ejected at runtime, and comes back after a probe.
+	dbutil.ExportEject(c, replica, driver.ErrBadConn)
+	if got := c.Reader(ctx); got != c.Primary() {
+		t.Error("reader want: the primary after ejecting the replica, got: a replica")
+	}
+	for c.Reader(ctx) == c.Primary() && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.Reader(ctx) != replica {
+		t.Error("reader want: the replica after a probe, got: another one")
+	}
+
+	// The unhealthy replica is restored by a probe after it recovers.
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[*sqlx.DB]bool)
+	for len(got) < 2 && ctx.Err() == nil {
+		got[c.Reader(ctx)] = true
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(got) != 2 || got[c.Primary()] {
+		t.Errorf("reads want: both replicas after recovery, got: %d handles", len(got))
+	}
+}
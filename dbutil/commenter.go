@@ -0,0 +1,178 @@
+package dbutil
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Keys of comments added by Commenter
+const (
+	CommentApplication = "application"
+	CommentJob         = "job"
+	CommentCaller      = "caller"
+	CommentTraceparent = "traceparent"
+)
+
+// Packages whose functions are skipped to find the caller of queries
+var internalPackages = []string{
+	"github.com/exaream/go-db/dbutil.",
+	"github.com/exaream/go-db/dbutil/",
+	"github.com/jmoiron/sqlx.",
+	"database/sql.",
+}
+
+// sqlCommentRegexp is a comment of sqlcommenter format. e.g. /*application='batch',job='daily'*/
+var sqlCommentRegexp = regexp.MustCompile(`^/\*[^=',*]+='[^']*'(?:,[^=',*]+='[^']*')*\*/$`)
+
+// jobKey is a context key of the job name.
+type jobKey struct{}
+
+// WithJob returns a context which makes Commenter tag queries with the job name.
+func WithJob(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, jobKey{}, name)
+}
+
+// jobName returns the job name of the context.
+func jobName(ctx context.Context) string {
+	v, _ := ctx.Value(jobKey{}).(string)
+	return v
+}
+
+var _ Interceptor = (*Commenter)(nil)
+
+// Commenter prepends sqlcommenter-style comments to queries to tell which application and job issued them
+// in performance_schema of MySQL and pg_stat_activity of PostgreSQL.
+// e.g. /*application='batch',caller='main.run',job='daily'*/ SELECT ...
+// Transaction lifecycle events and queries which already have sqlcommenter comments at the start or the end are not tagged.
+// The other comments such as optimizer hints and "--" in literals do not matter.
+type Commenter struct {
+	Application string
+	// Caller adds the function which called the helpers.
+	Caller bool
+	// Tags returns other comments of the context. e.g. dbtrace.Tags to add traceparent
+	Tags func(ctx context.Context) map[string]string
+}
+
+// NewCommenter returns Commenter whose application is application_name of DB config.
+// Sessions of PostgreSQL are named by the same value because OpenContext sets application_name
+// on every new connection, so Commenter does not set it.
+func NewCommenter(cfg *Config) *Commenter {
+	return &Commenter{Application: cfg.ApplicationName, Caller: true}
+}
+
+// Before prepends the comment to the query.
+func (c *Commenter) Before(ctx context.Context, ev *Event) (context.Context, error) {
+	if ev.Op != OpQuery && ev.Op != OpExec {
+		return ctx, nil
+	}
+	if hasSQLComment(ev.Query) {
+		return ctx, nil
+	}
+
+	tags := map[string]string{}
+	if c.Tags != nil {
+		for k, v := range c.Tags(ctx) {
+			tags[k] = v
+		}
+	}
+	if c.Application != "" {
+		tags[CommentApplication] = c.Application
+	}
+	if job := jobName(ctx); job != "" {
+		tags[CommentJob] = job
+	}
+	if c.Caller {
+		if caller := callerName(); caller != "" {
+			tags[CommentCaller] = caller
+		}
+	}
+
+	if comment := FormatComment(tags); comment != "" {
+		ev.Query = comment + " " + ev.Query
+	}
+	return ctx, nil
+}
+
+// After does nothing.
+func (c *Commenter) After(context.Context, *Event) {}
+
+// hasSQLComment reports whether the query starts or ends with a comment of sqlcommenter format.
+func hasSQLComment(query string) bool {
+	return StripSQLComment(query) != strings.TrimSpace(query)
+}
+
+// StripSQLComment returns the query without comments of sqlcommenter format at its start and end.
+// It is for grouping queries tagged by Commenter, e.g. labels of metrics.
+func StripSQLComment(query string) string {
+	query = strings.TrimSpace(query)
+	if strings.HasPrefix(query, "/*") {
+		if end := strings.Index(query, "*/"); end >= 0 && sqlCommentRegexp.MatchString(query[:end+2]) {
+			query = strings.TrimSpace(query[end+2:])
+		}
+	}
+
+	trimmed := strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if strings.HasSuffix(trimmed, "*/") {
+		if start := strings.LastIndex(trimmed, "/*"); start >= 0 && sqlCommentRegexp.MatchString(trimmed[start:]) {
+			semicolon := strings.TrimSpace(strings.TrimPrefix(query, trimmed))
+			query = strings.TrimSpace(trimmed[:start]) + semicolon
+		}
+	}
+	return query
+}
+
+// FormatComment returns a comment of sqlcommenter format in order of keys.
+// Keys and values are URL encoded, so they can not close the comment.
+func FormatComment(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = encodeComment(k) + "='" + encodeComment(tags[k]) + "'"
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+// encodeComment returns a key or a value of comments URL encoded.
+func encodeComment(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// callerName returns the function which called dbutil, sqlx or database/sql.
+func callerName() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !isInternal(frame.Function) {
+			return path.Base(frame.Function)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// isInternal reports whether the function belongs to dbutil, sqlx or database/sql.
+func isInternal(function string) bool {
+	for _, pkg := range internalPackages {
+		if strings.HasPrefix(function, pkg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package dbutil_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+func TestFormatComment(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		tags map[string]string
+		want string
+	}{
+		"sorted":  {map[string]string{"job": "daily", "application": "batch"}, `/*application='batch',job='daily'*/`},
+		"encoded": {map[string]string{"job": "it's */ DROP TABLE users; --"}, `/*job='it%27s%20%2A%2F%20DROP%20TABLE%20users%3B%20--'*/`},
+		"empty":   {map[string]string{"job": ""}, ""},
+		"nil":     {nil, ""},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := dbutil.FormatComment(tt.tags); got != tt.want {
+				t.Errorf("want: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// Queries with comments and literals like comments
+const (
+	querySelectHint     = `/* hint */ SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status;`
+	querySelectLiteral  = `SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status AND name NOT IN ('--', '/*');`
+	querySelectLeading  = `/*job='manual'*/ SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status;`
+	querySelectTrailing = `SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status /*job='manual'*/;`
+)
+
+func TestStripSQLComment(t *testing.T) {
+	t.Parallel()
+
+	const want = `SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status;`
+	cases := map[string]struct {
+		query string
+		want  string
+	}{
+		"leading":  {querySelectLeading, want},
+		"trailing": {querySelectTrailing, want},
+		"both":     {`/*job='manual'*/ SELECT 1 /*traceparent='00-1-2-01'*/`, "SELECT 1"},
+		"hint":     {querySelectHint, querySelectHint},
+		"literal":  {querySelectLiteral, querySelectLiteral},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := dbutil.StripSQLComment(tt.query); got != tt.want {
+				t.Errorf("want: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommenter(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		commenter *dbutil.Commenter
+		ctx       func(ctx context.Context) context.Context
+		run       func(ctx context.Context, db *dbutil.DB) ([]*User, error)
+		want      string
+	}{
+		"application and job": {
+			&dbutil.Commenter{Application: "batch"},
+			func(ctx context.Context) context.Context { return dbutil.WithJob(ctx, "daily") },
+			func(ctx context.Context, db *dbutil.DB) ([]*User, error) {
+				return dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+			},
+			"/*application='batch',job='daily'*/ SELECT ",
+		},
+		"caller": {
+			&dbutil.Commenter{Caller: true},
+			func(ctx context.Context) context.Context { return ctx },
+			func(ctx context.Context, db *dbutil.DB) ([]*User, error) {
+				return dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+			},
+			"/*caller='dbutil_test.TestCommenter.func",
+		},
+		"tags": {
+			&dbutil.Commenter{Tags: func(context.Context) map[string]string { return map[string]string{"route": "/users"} }},
+			func(ctx context.Context) context.Context { return ctx },
+			func(ctx context.Context, db *dbutil.DB) ([]*User, error) {
+				return dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+			},
+			"/*route='%2Fusers'*/ SELECT ",
+		},
+		"hint": {
+			&dbutil.Commenter{Application: "batch"},
+			func(ctx context.Context) context.Context { return ctx },
+			func(ctx context.Context, db *dbutil.DB) ([]*User, error) {
+				return dbutil.SelectContext[User](ctx, db, querySelectHint, map[string]any{"id": 1, "status": active})
+			},
+			"/*application='batch'*/ /* hint */ SELECT ",
+		},
+		"literal": {
+			&dbutil.Commenter{Application: "batch"},
+			func(ctx context.Context) context.Context { return ctx },
+			func(ctx context.Context, db *dbutil.DB) ([]*User, error) {
+				return dbutil.SelectContext[User](ctx, db, querySelectLiteral, map[string]any{"id": 1, "status": active})
+			},
+			"/*application='batch'*/ SELECT ",
+		},
+		"leading comment": {
+			&dbutil.Commenter{Application: "batch"},
+			func(ctx context.Context) context.Context { return ctx },
+			func(ctx context.Context, db *dbutil.DB) ([]*User, error) {
+				return dbutil.SelectContext[User](ctx, db, querySelectLeading, map[string]any{"id": 1, "status": active})
+			},
+			"/*job='manual'*/ SELECT ",
+		},
+		"trailing comment": {
+			&dbutil.Commenter{Application: "batch"},
+			func(ctx context.Context) context.Context { return ctx },
+			func(ctx context.Context, db *dbutil.DB) ([]*User, error) {
+				return dbutil.SelectContext[User](ctx, db, querySelectTrailing, map[string]any{"id": 1, "status": active})
+			},
+			"SELECT ",
+		},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+			defer cancel()
+
+			db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "commenter"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			var got string
+			record := dbutil.InterceptorFuncs{
+				AfterFunc: func(_ context.Context, ev *dbutil.Event) { got = ev.Query },
+			}
+
+			ctx = tt.ctx(ctx)
+			cdb := dbutil.NewDB(db, tt.commenter, record)
+			list, err := tt.run(ctx, cdb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 1 {
+				t.Errorf("users want: 1, got: %d", len(list))
+			}
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("want: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
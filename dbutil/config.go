@@ -1,14 +1,21 @@
 package dbutil
 
 import (
-	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"go.uber.org/multierr"
 )
 
 const (
@@ -19,6 +26,16 @@ const (
 	// PostgreSQL
 	pgsqlDBType = "pgsql"
 	pgsqlDriver = "pgx"
+	// SQLite
+	sqliteDBType      = "sqlite"
+	sqliteDriver      = "sqlite3"
+	sqliteMemory      = ":memory:"
+	sqliteBusyTimeout = 5000 // milliseconds
+	// Default values of MySQL driver
+	defaultCollation = "utf8mb4_bin"
+	defaultParseTime = true
+	// Default value of database/sql
+	defaultMaxIdleConns = 2
 )
 
 // DB config file
@@ -26,21 +43,52 @@ type ConfigFile struct {
 	Type    string
 	Path    string
 	Section string
+	DSN     string // Used instead of the file if it is not empty.
 }
 
 // DB config
 type Config struct {
 	Type     string
 	Host     string
-	Database string
+	Database string // File path or ":memory:" for SQLite
 	Username string
 	Password string
 	Port     uint16 // 1~65535
 	Protocol string
 	Tz       string
 	SSLMode  string // for PostgreSQL
-	Driver   string
-	DataSrc  string
+	URL      string // Connection URL. Other keys override its values.
+	// Unix domain socket path. Use a directory which has the socket file for PostgreSQL.
+	Socket string
+	// Multiple hosts for PostgreSQL such as "host1:5432,host2:5432" tried in order.
+	Hosts []string
+	// One of "any", "read-write", "read-only", "primary", "standby" and "prefer-standby" for PostgreSQL
+	TargetSessionAttrs string `mapstructure:"target_session_attrs"`
+	// Session settings applied on every new connection
+	StatementTimeout time.Duration     `mapstructure:"statement_timeout"`
+	LockTimeout      time.Duration     `mapstructure:"lock_timeout"`
+	ApplicationName  string            `mapstructure:"application_name"` // for PostgreSQL
+	Session          map[string]string // e.g. session.sql_mode = 'TRADITIONAL'
+	InitStatements   Statements        `mapstructure:"init_statements"`
+	// Driver settings for MySQL
+	Charset           string
+	Collation         string        // utf8mb4_bin by default
+	ParseTime         *bool         `mapstructure:"parse_time"` // true by default
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	InterpolateParams bool          `mapstructure:"interpolate_params"`
+	MultiStatements   bool          `mapstructure:"multi_statements"`
+	MaxAllowedPacket  int           `mapstructure:"max_allowed_packet"` // 0 means the server's value
+	// Connection pool settings which can be changed without reopening DB handle
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`     // 0 means unlimited
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`     // 0 means the default of database/sql (2)
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`  // 0 means forever
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"` // 0 means forever
+	// Params are passed to the driver as they are.
+	// e.g. system variables for MySQL, runtime parameters for PostgreSQL and query parameters for SQLite
+	Params  map[string]string
+	Driver  string `mapstructure:"-"`
+	DataSrc string `mapstructure:"-"`
 }
 
 // NewConfigFile returns DB config file.
@@ -52,8 +100,31 @@ func NewConfigFile(typ, path, section string) *ConfigFile {
 	}
 }
 
+// NewDSNConfigFile returns DB config file which has a connection URL or a native DSN.
+func NewDSNConfigFile(dsn string) *ConfigFile {
+	return &ConfigFile{DSN: dsn}
+}
+
+// Parse returns DB config by DB config file.
+func (f *ConfigFile) Parse() (*Config, error) {
+	if f.DSN != "" {
+		return ParseDSN(f.DSN)
+	}
+	return ParseConfig(f.Type, f.Path, f.Section)
+}
+
 // ParseConfig returns DB config by DB config file.
 func ParseConfig(typ, path, section string) (*Config, error) {
+	v, err := readConfigFile(typ, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseConfigSection(v, path, section)
+}
+
+// readConfigFile returns all settings of DB config file.
+func readConfigFile(typ, path string) (*viper.Viper, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, err
 	}
@@ -66,22 +137,92 @@ func ParseConfig(typ, path, section string) (*Config, error) {
 		return nil, err
 	}
 
+	return v, nil
+}
+
+// parseConfigSection returns DB config by a section of DB config file.
+func parseConfigSection(v *viper.Viper, path, section string) (*Config, error) {
 	sub := v.Sub(section)
 	if sub == nil {
-		return nil, errors.New("failed to parse config by section")
+		return nil, &ConfigError{Path: path, Section: section, Err: errors.New("failed to parse config by section")}
+	}
+
+	cfg, err := parseSection(sub)
+	if err != nil {
+		return nil, withLocation(err, path, section)
 	}
 
+	return cfg, nil
+}
+
+// parseSection returns DB config by settings of a section of DB config file.
+// Errors do not have the file path and section, so callers set them.
+func parseSection(sub *viper.Viper) (*Config, error) {
+	errs := validateKeys(sub)
+
 	var cfg *Config
-	if err := sub.Unmarshal(&cfg); err != nil {
+	hook := mapstructure.ComposeDecodeHookFunc(
+		stringToStatementsHookFunc(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)
+	if err := sub.Unmarshal(&cfg, viper.DecodeHook(hook)); err != nil {
+		return nil, multierr.Append(errs, &ConfigError{Err: err})
+	}
+
+	password, err := decodeSecret(cfg.Password)
+	if err != nil {
+		errs = multierr.Append(errs, &ConfigError{Key: "password", Err: err})
+	}
+	cfg.Password = password
+
+	if cfg.URL != "" {
+		merged, err := cfg.mergeURL()
+		if err != nil {
+			return nil, multierr.Append(errs, &ConfigError{Key: "url", Err: err})
+		}
+		cfg = merged
+	}
+
+	if errs = appendConfigErrors(errs, cfg.Validate()); errs != nil {
+		return nil, errs
+	}
+
+	if err := cfg.build(); err != nil {
 		return nil, err
 	}
 
-	password, err := base64.StdEncoding.DecodeString(cfg.Password)
+	return cfg, nil
+}
+
+// mergeURL returns DB config parsed from URL and overridden by the other keys.
+func (cfg *Config) mergeURL() (*Config, error) {
+	merged, err := parseURL(cfg.URL)
 	if err != nil {
 		return nil, err
 	}
-	cfg.Password = string(password)
 
+	dst := reflect.ValueOf(merged).Elem()
+	src := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+
+	return merged, nil
+}
+
+// Location returns the location of Tz, which is Asia/Tokyo by default.
+func (cfg *Config) Location() (*time.Location, error) {
+	if cfg.Tz == "" {
+		return time.LoadLocation(defaultTz)
+	}
+	return time.LoadLocation(cfg.Tz)
+}
+
+// build sets default values, driver and data source name.
+func (cfg *Config) build() error {
 	if cfg.Tz == "" {
 		cfg.Tz = defaultTz
 	}
@@ -91,17 +232,50 @@ func ParseConfig(typ, path, section string) (*Config, error) {
 		cfg.Driver = mysqlDriver
 		dsn, err := cfg.dataSrcMySQL()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		cfg.DataSrc = dsn
 	case pgsqlDBType:
 		cfg.Driver = pgsqlDriver
 		cfg.DataSrc = cfg.dataSrcPgSQL()
+	case sqliteDBType:
+		cfg.Driver = sqliteDriver
+		cfg.DataSrc = cfg.dataSrcSQLite()
 	default:
-		return nil, errors.New("unsupported DB type")
+		return errors.New("unsupported DB type")
 	}
 
-	return cfg, nil
+	return nil
+}
+
+// mysqlDriverParams are parameters of the MySQL driver by lowercase names.
+// Other params are system variables.
+var mysqlDriverParams = map[string]string{
+	"allowallfiles":            "allowAllFiles",
+	"allowcleartextpasswords":  "allowCleartextPasswords",
+	"allowfallbacktoplaintext": "allowFallbackToPlaintext",
+	"allownativepasswords":     "allowNativePasswords",
+	"allowoldpasswords":        "allowOldPasswords",
+	"checkconnliveness":        "checkConnLiveness",
+	"clientfoundrows":          "clientFoundRows",
+	"columnswithalias":         "columnsWithAlias",
+	"rejectreadonly":           "rejectReadOnly",
+	"serverpubkey":             "serverPubKey",
+	"timeout":                  "timeout",
+	"tls":                      "tls",
+}
+
+// mysqlConfigParams are parameters of the MySQL driver by lowercase names which have their own keys in DB config.
+var mysqlConfigParams = map[string]string{
+	"loc":               "tz",
+	"charset":           "charset",
+	"collation":         "collation",
+	"parsetime":         "parse_time",
+	"readtimeout":       "read_timeout",
+	"writetimeout":      "write_timeout",
+	"interpolateparams": "interpolate_params",
+	"multistatements":   "multi_statements",
+	"maxallowedpacket":  "max_allowed_packet",
 }
 
 // dataSrcMySQL returns data source name for MySQL.
@@ -110,21 +284,125 @@ func (cfg *Config) dataSrcMySQL() (string, error) {
 	if err != nil {
 		return "", err
 	}
+
+	collation := cfg.Collation
+	if collation == "" {
+		collation = defaultCollation
+	}
+
+	parseTime := defaultParseTime
+	if cfg.ParseTime != nil {
+		parseTime = *cfg.ParseTime
+	}
+
+	var params map[string]string
+	if len(cfg.Params) > 0 || cfg.Charset != "" {
+		params = make(map[string]string, len(cfg.Params)+1)
+		for k, v := range cfg.Params {
+			// Config files lowercase keys, but the driver only knows its parameters in camel case.
+			if name, ok := mysqlDriverParams[strings.ToLower(k)]; ok {
+				k = name
+			}
+			params[k] = v
+		}
+		if cfg.Charset != "" {
+			params["charset"] = cfg.Charset
+		}
+	}
+
 	c := mysql.Config{
-		DBName:    cfg.Database,
-		User:      cfg.Username,
-		Passwd:    cfg.Password,
-		Addr:      fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Net:       cfg.Protocol,
-		ParseTime: true,
-		Collation: "utf8mb4_bin",
-		Loc:       jst,
+		DBName:            cfg.Database,
+		User:              cfg.Username,
+		Passwd:            cfg.Password,
+		Addr:              net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port))),
+		Net:               cfg.Protocol,
+		ParseTime:         parseTime,
+		Collation:         collation,
+		Loc:               jst,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		InterpolateParams: cfg.InterpolateParams,
+		MultiStatements:   cfg.MultiStatements,
+		MaxAllowedPacket:  cfg.MaxAllowedPacket,
+		Params:            params,
+	}
+	if cfg.Socket != "" {
+		c.Net = "unix"
+		c.Addr = cfg.Socket
 	}
+
 	return c.FormatDSN(), nil
 }
 
 // dataSrcPgSQL returns data source name for PostgreSQL.
+// Params are appended as runtime parameters in order of names.
 func (cfg *Config) dataSrcPgSQL() string {
-	return fmt.Sprintf("host=%s port=%d user=%s dbname=%s password=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.Database, cfg.Password, cfg.SSLMode)
+	host, port := cfg.Host, strconv.Itoa(int(cfg.Port))
+	switch {
+	case cfg.Socket != "":
+		host = cfg.Socket
+	case len(cfg.Hosts) > 0:
+		host, port = cfg.pgsqlHosts()
+	}
+
+	dsn := fmt.Sprintf("host=%s", quoteKeyValue(host))
+	// The default port is used for the socket file name if it is not specified.
+	if cfg.Port != 0 || len(cfg.Hosts) > 0 {
+		dsn += fmt.Sprintf(" port=%s", quoteKeyValue(port))
+	}
+	dsn += fmt.Sprintf(" user=%s dbname=%s password=%s sslmode=%s",
+		quoteKeyValue(cfg.Username), quoteKeyValue(cfg.Database), quoteKeyValue(cfg.Password), quoteKeyValue(cfg.SSLMode))
+
+	if cfg.TargetSessionAttrs != "" {
+		dsn += fmt.Sprintf(" target_session_attrs=%s", quoteKeyValue(cfg.TargetSessionAttrs))
+	}
+
+	names := make([]string, 0, len(cfg.Params))
+	for name := range cfg.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dsn += fmt.Sprintf(" %s=%s", name, quoteKeyValue(cfg.Params[name]))
+	}
+
+	return dsn
+}
+
+// pgsqlHosts returns comma-separated hosts and ports of Hosts.
+// Port is used for a host which has no port.
+func (cfg *Config) pgsqlHosts() (string, string) {
+	hosts := make([]string, 0, len(cfg.Hosts))
+	ports := make([]string, 0, len(cfg.Hosts))
+
+	for _, addr := range cfg.Hosts {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, strconv.Itoa(int(cfg.Port))
+		}
+		hosts = append(hosts, host)
+		ports = append(ports, port)
+	}
+
+	return strings.Join(hosts, ","), strings.Join(ports, ",")
+}
+
+// dataSrcSQLite returns data source name for SQLite.
+// Transactions take a write lock immediately so that concurrent writers wait for each other.
+func (cfg *Config) dataSrcSQLite() string {
+	params := url.Values{}
+	for k, v := range cfg.Params {
+		params.Set(k, v)
+	}
+	params.Set("_loc", cfg.Tz)
+	params.Set("_busy_timeout", strconv.Itoa(sqliteBusyTimeout))
+	params.Set("_txlock", "immediate")
+	// Escape "?", "#" and "%" of the path because SQLite decodes the path of the file URI.
+	return fmt.Sprintf("file:%s?%s", (&url.URL{Path: cfg.Database}).EscapedPath(), params.Encode())
+}
+
+// isMemory reports whether DB is SQLite's in-memory database.
+func (cfg *Config) isMemory() bool {
+	return cfg.Type == sqliteDBType && cfg.Database == sqliteMemory
 }
@@ -2,9 +2,12 @@ package dbutil_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/exaream/go-db/dbutil"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v4"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -14,8 +17,9 @@ func TestParseConfig(t *testing.T) {
 		dbType string
 		path   string
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath},
+		"mysql":  {mysqlDBType, mysqlCfgPath},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath},
+		"sqlite": {sqliteDBType, sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -64,3 +68,111 @@ func TestParseConfigErr(t *testing.T) {
 		})
 	}
 }
+
+func TestParseConfigDriverMySQL(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := dbutil.ParseConfig(cfgType, mysqlCfgPath, driverSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := mysql.ParseDSN(cfg.DataSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		want any
+		got  any
+	}{
+		"charset":            {"utf8mb4", got.Params["charset"]},
+		"collation":          {"utf8mb4_general_ci", got.Collation},
+		"parse_time":         {false, got.ParseTime},
+		"read_timeout":       {30 * time.Second, got.ReadTimeout},
+		"write_timeout":      {10 * time.Second, got.WriteTimeout},
+		"interpolate_params": {true, got.InterpolateParams},
+		"multi_statements":   {true, got.MultiStatements},
+		"max_allowed_packet": {16777216, got.MaxAllowedPacket},
+		"params":             {"TRADITIONAL", got.Params["sql_mode"]},
+	}
+
+	for name, tt := range cases {
+		if tt.got != tt.want {
+			t.Errorf("%s want: %v, got: %v", name, tt.want, tt.got)
+		}
+	}
+}
+
+func TestParseConfigDriverPgSQL(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := dbutil.ParseConfig(cfgType, pgsqlCfgPath, driverSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pgx.ParseConfig(cfg.DataSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"search_path": "app", "work_mem": "64MB"}
+	if diff := cmp.Diff(want, got.RuntimeParams); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParseConfigHosts(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := dbutil.ParseConfig(cfgType, pgsqlCfgPath, hostsSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pgx.ParseConfig(cfg.DataSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := []string{got.Host}
+	for _, fb := range got.Fallbacks {
+		hosts = append(hosts, fb.Host)
+	}
+	if diff := cmp.Diff([]string{pgsqlHost, "go_db_pgsql_standby"}, hosts); diff != "" {
+		t.Error(diff)
+	}
+	if got.ValidateConnect == nil {
+		t.Error("target_session_attrs want: read-write, got: any")
+	}
+}
+
+func TestConfigLocation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		tz      string
+		want    string
+		wantErr bool
+	}{
+		"default": {"", "Asia/Tokyo", false},
+		"utc":     {"UTC", "UTC", false},
+		"unknown": {"Unknown/Zone", "", true},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loc, err := (&dbutil.Config{Tz: tt.tz}).Location()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error want: %t, got: %v", tt.wantErr, err)
+			}
+			if err == nil && loc.String() != tt.want {
+				t.Errorf("want: %s, got: %s", tt.want, loc)
+			}
+		})
+	}
+}
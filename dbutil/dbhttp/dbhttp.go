@@ -0,0 +1,164 @@
+// Package dbhttp provides an HTTP handler which serves health and metrics of DB handles.
+package dbhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+const (
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+// Handler serves the following endpoints of named DB handles.
+//
+//	/healthz: the results of the probe query in JSON, 503 if any of them fail
+//	/metrics: pool statistics and metrics of queries in Prometheus text exposition format
+type Handler struct {
+	mux      *http.ServeMux
+	names    []string
+	checkers map[string]*dbutil.HealthChecker
+	metrics  *Metrics
+}
+
+// NewHandler returns Handler of health checkers by names of DB handles.
+// metrics may be nil if metrics of queries are not needed.
+func NewHandler(checkers map[string]*dbutil.HealthChecker, metrics *Metrics) *Handler {
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := &Handler{mux: http.NewServeMux(), names: names, checkers: checkers, metrics: metrics}
+	h.mux.HandleFunc("/healthz", h.healthz)
+	h.mux.HandleFunc("/metrics", h.metricsz)
+
+	return h
+}
+
+// ServeHTTP serves the endpoints.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// health is a result of /healthz.
+type health struct {
+	Status      string                `json:"status"`
+	Connections map[string]connHealth `json:"connections"`
+}
+
+// connHealth is a result of a DB handle.
+type connHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthz serves the results of the probe query.
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	res := health{Status: statusOK, Connections: make(map[string]connHealth, len(h.names))}
+	for name, err := range h.check(r.Context()) {
+		if err != nil {
+			res.Status = statusError
+			res.Connections[name] = connHealth{Status: statusError, Error: dbutil.RedactDSN(err.Error())}
+			continue
+		}
+		res.Connections[name] = connHealth{Status: statusOK}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if res.Status != statusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// check runs the probe query of all DB handles at the same time.
+func (h *Handler) check(ctx context.Context) map[string]error {
+	type result struct {
+		name string
+		err  error
+	}
+
+	ch := make(chan result, len(h.names))
+	for _, name := range h.names {
+		go func(name string) {
+			ch <- result{name, h.checkers[name].Readiness(ctx)}
+		}(name)
+	}
+
+	results := make(map[string]error, len(h.names))
+	for range h.names {
+		r := <-ch
+		results[r.name] = r.err
+	}
+	return results
+}
+
+// poolMetrics are metrics of pool statistics.
+var poolMetrics = []struct {
+	name string
+	typ  string
+	help string
+}{
+	{"dbutil_pool_max_open_connections", "gauge", "Maximum number of open connections."},
+	{"dbutil_pool_open_connections", "gauge", "The number of established connections both in use and idle."},
+	{"dbutil_pool_in_use_connections", "gauge", "The number of connections currently in use."},
+	{"dbutil_pool_idle_connections", "gauge", "The number of idle connections."},
+	{"dbutil_pool_wait_count_total", "counter", "The total number of connections waited for."},
+	{"dbutil_pool_wait_duration_seconds_total", "counter", "The total time blocked waiting for a new connection."},
+	{"dbutil_pool_max_idle_closed_total", "counter", "The total number of connections closed due to max_idle_conns."},
+	{"dbutil_pool_max_idle_time_closed_total", "counter", "The total number of connections closed due to conn_max_idle_time."},
+	{"dbutil_pool_max_lifetime_closed_total", "counter", "The total number of connections closed due to conn_max_lifetime."},
+}
+
+// metricsz serves pool statistics and metrics of queries.
+func (h *Handler) metricsz(w http.ResponseWriter, _ *http.Request) {
+	values := make(map[string][]string, len(poolMetrics))
+	for _, name := range h.names {
+		s := h.checkers[name].DB.Stats()
+		label := "{db=" + quoteLabel(name) + "}"
+		for i, v := range []string{
+			fmt.Sprint(s.MaxOpenConnections),
+			fmt.Sprint(s.OpenConnections),
+			fmt.Sprint(s.InUse),
+			fmt.Sprint(s.Idle),
+			fmt.Sprint(s.WaitCount),
+			formatFloat(s.WaitDuration.Seconds()),
+			fmt.Sprint(s.MaxIdleClosed),
+			fmt.Sprint(s.MaxIdleTimeClosed),
+			fmt.Sprint(s.MaxLifetimeClosed),
+		} {
+			m := poolMetrics[i].name
+			values[m] = append(values[m], m+label+" "+v)
+		}
+	}
+
+	var b strings.Builder
+	for _, m := range poolMetrics {
+		writeHeader(&b, m.name, m.typ, m.help)
+		for _, line := range values[m.name] {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	if h.metrics != nil {
+		if _, err := h.metrics.WriteTo(&b); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = io.WriteString(w, b.String())
+}
@@ -0,0 +1,172 @@
+package dbhttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/exaream/go-db/dbutil/dbhttp"
+)
+
+const timeout = 5
+
+// openSQLite returns a health checker of SQLite's in-memory database.
+func openSQLite(ctx context.Context, t *testing.T) *dbutil.HealthChecker {
+	t.Helper()
+
+	cfg, err := dbutil.ParseDSN("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := dbutil.OpenContext(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return dbutil.NewHealthChecker(db)
+}
+
+// get returns the status code and body of the path.
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(body)
+}
+
+func TestHealthz(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	broken := openSQLite(ctx, t)
+	broken.ProbeQuery = "SELECT * FROM dummy"
+
+	cases := map[string]struct {
+		checkers map[string]*dbutil.HealthChecker
+		code     int
+		want     map[string]string
+	}{
+		"ok":    {map[string]*dbutil.HealthChecker{"primary": openSQLite(ctx, t)}, http.StatusOK, map[string]string{"primary": "ok"}},
+		"error": {map[string]*dbutil.HealthChecker{"primary": openSQLite(ctx, t), "replica": broken}, http.StatusServiceUnavailable, map[string]string{"primary": "ok", "replica": "error"}},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			code, body := get(t, dbhttp.NewHandler(tt.checkers, nil), "/healthz")
+			if code != tt.code {
+				t.Errorf("status code want: %d, got: %d", tt.code, code)
+			}
+
+			var res struct {
+				Connections map[string]struct{ Status string }
+			}
+			if err := json.Unmarshal([]byte(body), &res); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got := res.Connections[name].Status; got != want {
+					t.Errorf("%s want: %s, got: %s (%s)", name, want, got, body)
+				}
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	checker := openSQLite(ctx, t)
+	metrics := dbhttp.NewMetrics(0.1, 1)
+
+	query := `SELECT 1
+  FROM users`
+	metrics.Observe("primary", query, 50*time.Millisecond, nil)
+	// Comments of sqlcommenter format are not labeled.
+	metrics.Observe("primary", `/*traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/ `+query, 500*time.Millisecond, nil)
+	metrics.Observe("primary", query+` /*traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/`, 2*time.Second, errors.New("timeout"))
+
+	code, body := get(t, dbhttp.NewHandler(map[string]*dbutil.HealthChecker{"primary": checker}, metrics), "/metrics")
+	if code != http.StatusOK {
+		t.Errorf("status code want: %d, got: %d", http.StatusOK, code)
+	}
+
+	labels := `db="primary",query="SELECT 1 FROM users"`
+	for _, want := range []string{
+		"# TYPE dbutil_pool_open_connections gauge",
+		`dbutil_pool_open_connections{db="primary"} 1`,
+		`dbutil_pool_max_open_connections{db="primary"} 1`,
+		"# TYPE dbutil_queries_total counter",
+		`dbutil_queries_total{` + labels + `,status="ok"} 2`,
+		`dbutil_queries_total{` + labels + `,status="error"} 1`,
+		"# TYPE dbutil_query_duration_seconds histogram",
+		`dbutil_query_duration_seconds_bucket{` + labels + `,le="0.1"} 1`,
+		`dbutil_query_duration_seconds_bucket{` + labels + `,le="1"} 2`,
+		`dbutil_query_duration_seconds_bucket{` + labels + `,le="+Inf"} 3`,
+		`dbutil_query_duration_seconds_sum{` + labels + `} 2.55`,
+		`dbutil_query_duration_seconds_count{` + labels + `} 3`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("want: %s, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	checker := openSQLite(ctx, t)
+	metrics := dbhttp.NewMetrics()
+	db := dbutil.NewDB(checker.DB, metrics.Interceptor("primary"))
+
+	if _, err := db.ExecContext(ctx, "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "SELECT * FROM dummy"); err == nil {
+		t.Fatal("want: error, got: nil")
+	}
+
+	var b strings.Builder
+	if _, err := metrics.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`dbutil_queries_total{db="primary",query="SELECT 1",status="ok"} 1`,
+		`dbutil_queries_total{db="primary",query="SELECT * FROM dummy",status="error"} 1`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("want: %s, got:\n%s", want, b.String())
+		}
+	}
+}
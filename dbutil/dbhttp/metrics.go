@@ -0,0 +1,158 @@
+package dbhttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+// DefaultBuckets are upper bounds of latency histograms in seconds.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics has counters and latency histograms of queries.
+type Metrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	queries map[queryKey]*queryStats
+}
+
+// queryKey is labels of a query.
+type queryKey struct {
+	db    string
+	query string
+}
+
+// queryStats is counters and a latency histogram of a query.
+type queryStats struct {
+	ok     uint64
+	errors uint64
+	counts []uint64 // cumulative counts by buckets
+	sum    float64
+}
+
+// NewMetrics returns Metrics with latency buckets in seconds.
+// DefaultBuckets are used if no buckets are given.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Metrics{buckets: buckets, queries: make(map[queryKey]*queryStats)}
+}
+
+// Observe records a query run on the named DB.
+// Queries are labeled with whitespace collapsed and without comments of sqlcommenter format,
+// so use constant queries to keep labels few.
+func (m *Metrics) Observe(db, query string, d time.Duration, err error) {
+	key := queryKey{db: db, query: strings.Join(strings.Fields(dbutil.StripSQLComment(query)), " ")}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.queries[key]
+	if !ok {
+		s = &queryStats{counts: make([]uint64, len(m.buckets))}
+		m.queries[key] = s
+	}
+
+	if err != nil {
+		s.errors++
+	} else {
+		s.ok++
+	}
+
+	sec := d.Seconds()
+	s.sum += sec
+	for i, le := range m.buckets {
+		if sec <= le {
+			s.counts[i]++
+		}
+	}
+}
+
+// Interceptor returns dbutil.Interceptor which observes queries run on the named DB.
+// Transaction lifecycle events are not observed.
+func (m *Metrics) Interceptor(db string) dbutil.Interceptor {
+	return dbutil.InterceptorFuncs{
+		AfterFunc: func(_ context.Context, ev *dbutil.Event) {
+			if ev.Op == dbutil.OpQuery || ev.Op == dbutil.OpExec {
+				m.Observe(db, ev.Query, ev.Duration, ev.Err)
+			}
+		},
+	}
+}
+
+// WriteTo writes metrics of queries in Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]queryKey, 0, len(m.queries))
+	for key := range m.queries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].db != keys[j].db {
+			return keys[i].db < keys[j].db
+		}
+		return keys[i].query < keys[j].query
+	})
+
+	var b strings.Builder
+	writeHeader(&b, "dbutil_queries_total", "counter", "The number of queries.")
+	for _, key := range keys {
+		s := m.queries[key]
+		fmt.Fprintf(&b, "dbutil_queries_total{%s,status=\"ok\"} %d\n", key.labels(), s.ok)
+		fmt.Fprintf(&b, "dbutil_queries_total{%s,status=\"error\"} %d\n", key.labels(), s.errors)
+	}
+
+	writeHeader(&b, "dbutil_query_duration_seconds", "histogram", "Latency of queries in seconds.")
+	for _, key := range keys {
+		s := m.queries[key]
+		for i, le := range m.buckets {
+			fmt.Fprintf(&b, "dbutil_query_duration_seconds_bucket{%s,le=%q} %d\n", key.labels(), formatFloat(le), s.counts[i])
+		}
+		fmt.Fprintf(&b, "dbutil_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), s.ok+s.errors)
+		fmt.Fprintf(&b, "dbutil_query_duration_seconds_sum{%s} %s\n", key.labels(), formatFloat(s.sum))
+		fmt.Fprintf(&b, "dbutil_query_duration_seconds_count{%s} %d\n", key.labels(), s.ok+s.errors)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labels returns labels of the query.
+func (k queryKey) labels() string {
+	return fmt.Sprintf("db=%s,query=%s", quoteLabel(k.db), quoteLabel(k.query))
+}
+
+// writeHeader writes HELP and TYPE lines of a metric.
+func writeHeader(b *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labelReplacer escapes label values of Prometheus text exposition format.
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel returns a quoted label value.
+func quoteLabel(s string) string {
+	return `"` + labelReplacer.Replace(s) + `"`
+}
+
+// formatFloat returns a float in Prometheus text exposition format.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,122 @@
+// Package dbtrace provides an interceptor which traces queries and transactions of dbutil through OpenTelemetry.
+package dbtrace
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/exaream/go-db/dbutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "github.com/exaream/go-db/dbutil/dbtrace"
+	// rowsAffectedKey is the number of rows affected by the query, which semantic conventions do not define.
+	rowsAffectedKey = attribute.Key("db.rows_affected")
+)
+
+// Values of db.system by drivers
+var systems = map[string]attribute.KeyValue{
+	"mysql":   semconv.DBSystemMySQL,
+	"pgx":     semconv.DBSystemPostgreSQL,
+	"sqlite3": semconv.DBSystemSqlite,
+}
+
+// Patterns of literals in queries
+var (
+	// e.g. 'it''s'
+	stringRegexp = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	// e.g. 1, 1.5 but not $1 or t1
+	numberRegexp = regexp.MustCompile(`(^|[^\w$.])(?:\d+(?:\.\d+)?)\b`)
+)
+
+var _ dbutil.Interceptor = (*Interceptor)(nil)
+
+// Interceptor starts a span of each query and transaction event, which is a child of the span in the context.
+// Spans have db.system, db.name, db.statement without literals, the rows affected and the error.
+// db.rows_affected is set only for exec because spans of queries end before their rows are read,
+// so they do not include the time and errors of reading rows either.
+type Interceptor struct {
+	TracerProvider trace.TracerProvider // the global TracerProvider if it is nil
+	Database       string               // db.name
+}
+
+// NewInterceptor returns Interceptor of the database of DB config.
+func NewInterceptor(cfg *dbutil.Config) *Interceptor {
+	return &Interceptor{Database: cfg.Database}
+}
+
+// Before starts a span of the operation.
+func (i *Interceptor) Before(ctx context.Context, ev *dbutil.Event) (context.Context, error) {
+	provider := i.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	operation := strings.ToUpper(string(ev.Op))
+	attrs := []attribute.KeyValue{}
+	if ev.DB != nil {
+		if system, ok := systems[ev.DB.DriverName()]; ok {
+			attrs = append(attrs, system)
+		}
+	}
+	if i.Database != "" {
+		attrs = append(attrs, semconv.DBNameKey.String(i.Database))
+	}
+	if ev.Op == dbutil.OpQuery || ev.Op == dbutil.OpExec {
+		if fields := strings.Fields(ev.Query); len(fields) > 0 {
+			operation = strings.ToUpper(fields[0])
+		}
+		attrs = append(attrs, semconv.DBStatementKey.String(Sanitize(ev.Query)))
+	}
+	attrs = append(attrs, semconv.DBOperationKey.String(operation))
+
+	name := operation
+	if i.Database != "" {
+		name += " " + i.Database
+	}
+
+	ctx, _ = provider.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, nil
+}
+
+// After ends the span of the operation with its result.
+func (i *Interceptor) After(ctx context.Context, ev *dbutil.Event) {
+	span := trace.SpanFromContext(ctx)
+	if ev.RowsAffected >= 0 {
+		span.SetAttributes(rowsAffectedKey.Int64(ev.RowsAffected))
+	}
+	if ev.Err != nil {
+		span.RecordError(ev.Err)
+		span.SetStatus(codes.Error, ev.Err.Error())
+	}
+	span.End()
+}
+
+// Sanitize returns the query whose string and number literals are replaced with "?".
+// Placeholders such as ? and $1 are kept as they are.
+func Sanitize(query string) string {
+	query = stringRegexp.ReplaceAllString(query, "?")
+	return numberRegexp.ReplaceAllString(query, "${1}?")
+}
+
+// Tags returns traceparent of the span in the context for dbutil.Commenter.
+// Put Commenter after Interceptor to tag queries with their own spans.
+func Tags(ctx context.Context) map[string]string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return map[string]string{
+		dbutil.CommentTraceparent: fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags()),
+	}
+}
@@ -0,0 +1,162 @@
+package dbtrace_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/exaream/go-db/dbutil/dbtrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const timeout = 5
+
+const (
+	queryCreate = `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, status INTEGER);`
+	querySelect = `SELECT * FROM users WHERE id = :id AND name = 'admin';`
+	queryUpdate = `UPDATE users SET status = 1 WHERE id = :id;`
+)
+
+type User struct {
+	ID     int64  `db:"id"`
+	Name   string `db:"name"`
+	Status int    `db:"status"`
+}
+
+func TestInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	cfg, err := dbutil.ParseDSN("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlxDB, err := dbutil.OpenContext(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlxDB.Close()
+	if _, err := sqlxDB.ExecContext(ctx, queryCreate); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	db := dbutil.NewDB(sqlxDB, &dbtrace.Interceptor{TracerProvider: provider, Database: "main"})
+
+	ctx, parent := provider.Tracer("test").Start(ctx, "parent")
+	if _, err := dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbutil.UpdateTxContext(ctx, tx, queryUpdate, map[string]any{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbutil.SelectContext[User](ctx, db, "SELECT * FROM dummy;", nil); err == nil {
+		t.Fatal("want: error, got: nil")
+	}
+	parent.End()
+
+	type span struct {
+		name      string
+		statement string
+		rows      int64 // -1 if there is no attribute, which is the case of queries
+		err       bool
+	}
+	want := []span{
+		{"SELECT main", "SELECT * FROM users WHERE id = ? AND name = ?;", -1, false},
+		{"BEGIN main", "", -1, false},
+		{"UPDATE main", "UPDATE users SET status = ? WHERE id = ?;", 0, false},
+		{"COMMIT main", "", -1, false},
+		{"SELECT main", "SELECT * FROM dummy;", -1, true},
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != len(want)+1 {
+		t.Fatalf("spans want: %d, got: %d", len(want)+1, len(spans))
+	}
+	for i, w := range want {
+		s := spans[i]
+		if s.Name() != w.name {
+			t.Errorf("name want: %s, got: %s", w.name, s.Name())
+		}
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s: want: a child of the parent span, got: %s", s.Name(), s.Parent().SpanID())
+		}
+
+		attrs := attribute.NewSet(s.Attributes()...)
+		if v, _ := attrs.Value("db.system"); v.AsString() != "sqlite" {
+			t.Errorf("%s: db.system want: sqlite, got: %s", s.Name(), v.Emit())
+		}
+		if v, _ := attrs.Value("db.name"); v.AsString() != "main" {
+			t.Errorf("%s: db.name want: main, got: %s", s.Name(), v.Emit())
+		}
+		if v, _ := attrs.Value("db.statement"); v.AsString() != w.statement {
+			t.Errorf("%s: db.statement want: %s, got: %s", s.Name(), w.statement, v.Emit())
+		}
+		rows := int64(-1)
+		if v, ok := attrs.Value("db.rows_affected"); ok {
+			rows = v.AsInt64()
+		}
+		if rows != w.rows {
+			t.Errorf("%s: db.rows_affected want: %d, got: %d", s.Name(), w.rows, rows)
+		}
+		if (s.Status().Code == codes.Error) != w.err {
+			t.Errorf("%s: error status want: %t, got: %v", s.Name(), w.err, s.Status())
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query string
+		want  string
+	}{
+		"string":      {`SELECT * FROM users WHERE name = 'it''s' AND email = 'a\'b'`, `SELECT * FROM users WHERE name = ? AND email = ?`},
+		"number":      {`SELECT * FROM users WHERE id = 10 AND score > 1.5`, `SELECT * FROM users WHERE id = ? AND score > ?`},
+		"placeholder": {`SELECT * FROM t1 WHERE id = $1 AND status = ?`, `SELECT * FROM t1 WHERE id = $1 AND status = ?`},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := dbtrace.Sanitize(tt.query); got != tt.want {
+				t.Errorf("want: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "parent")
+	defer span.End()
+
+	sc := span.SpanContext()
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if got := dbtrace.Tags(ctx)[dbutil.CommentTraceparent]; got != want {
+		t.Errorf("want: %s, got: %s", want, got)
+	}
+
+	if got := dbtrace.Tags(context.Background()); got != nil {
+		t.Errorf("want: nil, got: %v", got)
+	}
+}
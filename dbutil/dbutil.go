@@ -1,20 +1,35 @@
-// Package dbutil provides general purpose functions for operating MySQL and PostgreSQL.
+// Package dbutil provides general purpose functions for operating MySQL, PostgreSQL and SQLite.
 package dbutil
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
 	"go.uber.org/multierr"
 )
 
-type stringConstant string
+// Query is a query of the helpers which is a constant, loaded by LoadQueries or built by Builder.
+// Untyped string constants are converted to it implicitly, and queries of Queries and Builder
+// can be kept in fields and variables of it. Do not convert strings built at runtime to it.
+type Query string
+
+// Transaction is a transaction which the helpers run queries on.
+// *sqlx.Tx and *Tx implement it.
+type Transaction interface {
+	sqlx.ExtContext
+	Rollback() error
+}
 
 // NewDBContext returns DB handle.
 func NewDBContext(ctx context.Context, f *ConfigFile) (*sqlx.DB, error) {
-	cfg, err := ParseConfig(f.Type, f.Path, f.Section)
+	cfg, err := f.Parse()
 	if err != nil {
 		return nil, err
 	}
@@ -28,23 +43,65 @@ func NewDBContext(ctx context.Context, f *ConfigFile) (*sqlx.DB, error) {
 }
 
 // OpenContext returns DB handle.
+// Session settings of DB config are applied on every new connection.
 // See: http://dsas.blog.klab.org/archives/52191467.html
-func OpenContext(ctx context.Context, cfg *Config) (db *sqlx.DB, err error) {
-	db, err = sqlx.Open(cfg.Driver, cfg.DataSrc)
-
+func OpenContext(ctx context.Context, cfg *Config) (*sqlx.DB, error) {
+	db, err := open(cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	if err := db.PingContext(ctx); err != nil {
+		return nil, multierr.Append(err, db.Close())
+	}
+
+	return db, nil
+}
+
+// open returns DB handle without connecting to DB.
+func open(cfg *Config) (db *sqlx.DB, err error) {
+	stmts, err := cfg.sessionStatements()
+	if err != nil {
 		return nil, err
 	}
 
+	if len(stmts) > 0 {
+		connector, err := cfg.connector(stmts)
+		if err != nil {
+			return nil, err
+		}
+		db = sqlx.NewDb(sql.OpenDB(connector), cfg.Driver)
+	} else if db, err = sqlx.Open(cfg.Driver, cfg.DataSrc); err != nil {
+		return nil, err
+	}
+
+	cfg.setPool(db)
+
 	return db, nil
 }
 
+// setPool applies connection pool settings to DB handle.
+func (cfg *Config) setPool(db *sqlx.DB) {
+	maxIdle := cfg.MaxIdleConns
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	// Each connection to SQLite's in-memory database has its own database,
+	// so all queries share a single connection.
+	if cfg.isMemory() {
+		db.SetMaxOpenConns(1)
+	}
+}
+
 // SelectContext runs SELECT and returns the results.
-func SelectContext[T any](ctx context.Context, db *sqlx.DB, query stringConstant, args map[string]any) ([]*T, error) {
+// db is *sqlx.DB or *Cluster which runs SELECT on a replica.
+func SelectContext[T any](ctx context.Context, db sqlx.ExtContext, query Query, args map[string]any) ([]*T, error) {
 	rows, err := sqlx.NamedQueryContext(ctx, db, string(query), args)
 	if err != nil {
 		return nil, err
@@ -62,8 +119,32 @@ func SelectContext[T any](ctx context.Context, db *sqlx.DB, query stringConstant
 	return list, nil
 }
 
+// GetContext runs SELECT and returns the first result.
+// It returns sql.ErrNoRows if there are no results.
+func GetContext[T any](ctx context.Context, db sqlx.ExtContext, query Query, args map[string]any) (*T, error) {
+	rows, err := sqlx.NamedQueryContext(ctx, db, string(query), args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	var row T
+	if err := rows.StructScan(&row); err != nil {
+		return nil, err
+	}
+
+	return &row, nil
+}
+
 // SelectTxContext runs SELECT and returns the results on transaction.
-func SelectTxContext[T any](ctx context.Context, tx *sqlx.Tx, query stringConstant, args map[string]any) ([]*T, error) {
+func SelectTxContext[T any](ctx context.Context, tx Transaction, query Query, args map[string]any) ([]*T, error) {
 	rows, err := sqlx.NamedQueryContext(ctx, tx, string(query), args)
 	if err != nil {
 		return nil, err
@@ -82,26 +163,97 @@ func SelectTxContext[T any](ctx context.Context, tx *sqlx.Tx, query stringConsta
 }
 
 // UpdateTxContext runs UPDATE on transaction.
-func UpdateTxContext(ctx context.Context, tx *sqlx.Tx, query stringConstant, args map[string]any) (int64, error) {
-	result, err := sqlx.NamedExecContext(ctx, tx, string(query), args)
+// Use UpdateRowTxContext to set columns of autoupdate option and to check versions.
+func UpdateTxContext(ctx context.Context, tx Transaction, query Query, args map[string]any) (int64, error) {
+	result, err := namedExecTx(ctx, tx, string(query), args)
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return num, nil
+}
+
+// namedExecTx runs the query with named parameters of the arg on transaction.
+// It rolls back the transaction if the query fails.
+func namedExecTx(ctx context.Context, tx Transaction, query string, arg any) (sql.Result, error) {
+	result, err := sqlx.NamedExecContext(ctx, tx, query, arg)
+	if err != nil {
+		return nil, multierr.Append(err, tx.Rollback())
+	}
+	return result, nil
+}
+
+// UpdateRowTxContext runs UPDATE whose named parameters are columns of the row on transaction.
+// Columns of autoupdate option are bound to the current time in the location of WithLocation, or the default Tz of Config.
+// If T has a column of version option, the query must check and increment it for optimistic locking.
+// e.g. "UPDATE users SET name = :name, version = version + 1 WHERE id = :id AND version = :version"
+// It returns *ConflictError if no rows are updated then.
+// The row is set the timestamps and the incremented version only if it is updated.
+func UpdateRowTxContext[T any](ctx context.Context, tx Transaction, query Query, row *T) (int64, error) {
+	meta, err := tableMetaOf(reflect.TypeOf(row).Elem())
 	if err != nil {
 		return 0, multierr.Append(err, tx.Rollback())
 	}
 
+	updated := *row
+	v := reflect.ValueOf(&updated).Elem()
+	if meta.autoCols {
+		meta.setTimestamps(v, nowIn(ctx), false)
+	}
+
+	result, err := namedExecTx(ctx, tx, string(query), &updated)
+	if err != nil {
+		return 0, err
+	}
 	num, err := result.RowsAffected()
 	if err != nil {
 		return 0, err
 	}
+	if num == 0 {
+		if meta.version == nil {
+			return 0, nil
+		}
+		return 0, multierr.Append(meta.conflict(v), tx.Rollback())
+	}
+
+	if meta.version != nil {
+		version := v.FieldByIndex(meta.version.index)
+		if version.CanInt() {
+			version.SetInt(version.Int() + 1)
+		} else {
+			version.SetUint(version.Uint() + 1)
+		}
+	}
+	*row = updated
 
 	return num, nil
 }
 
 // BulkInsertTxContext executes Bulk Insert on context and transaction.
-func BulkInsertTxContext[T any](ctx context.Context, tx *sqlx.Tx,
-	fn func(i, j int) []*T, query stringConstant, min, max, chunkSize int) (int64, error) {
+// Zero columns of autocreate and autoupdate options of T are set to the current time
+// in the location of WithLocation, or the default Tz of Config.
+func BulkInsertTxContext[T any](ctx context.Context, tx Transaction,
+	fn func(i, j int) []*T, query Query, min, max, chunkSize int) (int64, error) {
 	var i int
 	var total int64
 
+	var meta *tableMeta
+	var now time.Time
+	if typ := reflect.TypeOf((*T)(nil)).Elem(); typ.Kind() == reflect.Struct {
+		m, err := tableMetaOf(typ)
+		if err != nil {
+			return 0, multierr.Append(err, tx.Rollback())
+		}
+		if m.autoCols {
+			now, meta = nowIn(ctx), m
+		}
+	}
+
 	queryStr := string(query)
 	for i = min; i <= max; i += chunkSize {
 		j := i + chunkSize - min
@@ -109,17 +261,67 @@ func BulkInsertTxContext[T any](ctx context.Context, tx *sqlx.Tx,
 			j = max
 		}
 
-		result, err := tx.NamedExecContext(ctx, queryStr, fn(i, j))
+		rows := fn(i, j)
+		if meta != nil {
+			for _, row := range rows {
+				meta.setTimestamps(reflect.ValueOf(row).Elem(), now, true)
+			}
+		}
+
+		num, err := namedExecBatches(ctx, tx, queryStr, rows)
 		if err != nil {
 			return 0, multierr.Append(err, tx.Rollback())
 		}
+		total += num
+	}
+
+	return total, nil
+}
 
+// Maximum numbers of bound variables in a statement by drivers
+var maxBindVars = map[string]int{
+	mysqlDriver:  65535,
+	pgsqlDriver:  65535,
+	sqliteDriver: 32766, // SQLITE_MAX_VARIABLE_NUMBER since SQLite 3.32.0
+}
+
+// namedExecBatches runs multi-row INSERT of the rows, and returns the number of affected rows.
+// The rows are split so that each statement has no more bound variables than the driver accepts.
+func namedExecBatches[T any](ctx context.Context, db sqlx.ExtContext, query string, rows []*T) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	size := len(rows)
+	if limit, ok := maxBindVars[db.DriverName()]; ok {
+		_, args, err := sqlx.Named(query, rows[0])
+		if err != nil {
+			return 0, err
+		}
+		if n := len(args); n > 0 && size*n > limit {
+			size = limit / n
+		}
+		if size < 1 {
+			return 0, fmt.Errorf("a row has more bound variables than %d of the driver", limit)
+		}
+	}
+
+	var total int64
+	for i := 0; i < len(rows); i += size {
+		j := i + size
+		if j > len(rows) {
+			j = len(rows)
+		}
+
+		result, err := sqlx.NamedExecContext(ctx, db, query, rows[i:j])
+		if err != nil {
+			return total, err
+		}
 		num, err := result.RowsAffected()
 		if err != nil {
-			return 0, multierr.Append(err, tx.Rollback())
+			return total, err
 		}
 		total += num
 	}
-
 	return total, nil
 }
@@ -2,6 +2,8 @@ package dbutil_test
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,8 +27,9 @@ func TestNewDBContext(t *testing.T) {
 	cases := map[string]struct {
 		path string
 	}{
-		"mysql": {mysqlCfgPath},
-		"pgsql": {pgsqlCfgPath},
+		"mysql":  {mysqlCfgPath},
+		"pgsql":  {pgsqlCfgPath},
+		"sqlite": {sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -65,8 +68,9 @@ func TestOpenContext(t *testing.T) {
 	cases := map[string]struct {
 		path string
 	}{
-		"mysql": {mysqlCfgPath},
-		"pgsql": {pgsqlCfgPath},
+		"mysql":  {mysqlCfgPath},
+		"pgsql":  {pgsqlCfgPath},
+		"sqlite": {sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -106,8 +110,9 @@ func TestOpenContextErr(t *testing.T) {
 		dbType string
 		path   string
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath},
+		"mysql":  {mysqlDBType, mysqlCfgPath},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath},
+		"sqlite": {sqliteDBType, sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -125,6 +130,9 @@ func TestOpenContextErr(t *testing.T) {
 			cfg.Port = dummyPort
 
 			switch tt.dbType {
+			case sqliteDBType:
+				cfg.Database = filepath.Join(dummy, dummy)
+				cfg.DataSrc = dbutil.ExportDataSrcSQLite(cfg)
 			case mysqlDBType:
 				dsn, err := dbutil.ExportDataSrcMySQL(cfg)
 				if err != nil {
@@ -147,8 +155,9 @@ func TestSelectContext(t *testing.T) {
 		dbType string
 		path   string
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath},
+		"mysql":  {mysqlDBType, mysqlCfgPath},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath},
+		"sqlite": {sqliteDBType, sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -185,8 +194,9 @@ func TestSelectTxContext(t *testing.T) {
 		dbType string
 		path   string
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath},
+		"mysql":  {mysqlDBType, mysqlCfgPath},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath},
+		"sqlite": {sqliteDBType, sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -230,8 +240,9 @@ func TestUpdateTxContext(t *testing.T) {
 		dbType string
 		path   string
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath},
+		"mysql":  {mysqlDBType, mysqlCfgPath},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath},
+		"sqlite": {sqliteDBType, sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -272,10 +283,16 @@ func TestUpdateTxContext(t *testing.T) {
 
 func TestBulkInsertTxContext(t *testing.T) {
 	cases := map[string]struct {
-		path string
+		dbType    string
+		path      string
+		max       int
+		chunkSize int
 	}{
-		"mysql": {mysqlCfgPath},
-		"pgsql": {pgsqlCfgPath},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 5000, 1000},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 5000, 1000},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 5000, 1000},
+		// 10000 rows of 5 columns exceed the limit of bound variables of SQLite.
+		"sqlite large chunk": {sqliteDBType, sqliteCfgPath, 10000, 10000},
 	}
 
 	for name, tt := range cases {
@@ -283,6 +300,8 @@ func TestBulkInsertTxContext(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
+			prepareDB(t, tt.dbType, beforeSQLPath)
+
 			ctx := context.Background()
 			f := dbutil.NewConfigFile(cfgType, tt.path, cfgSection)
 
@@ -291,16 +310,15 @@ func TestBulkInsertTxContext(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			var min, max, chunkSize = 1, 5000, 1000
 			tx := db.MustBeginTx(ctx, nil)
 
-			num, err := dbutil.BulkInsertTxContext(ctx, tx, fakeUsers, queryInsert, min, max, chunkSize)
+			num, err := dbutil.BulkInsertTxContext(ctx, tx, fakeUsers, queryInsert, 1, tt.max, tt.chunkSize)
 			if err != nil {
-				t.Error(err)
+				t.Fatal(err)
 			}
 
-			if num != int64(max) {
-				t.Errorf("num want: %d, got: %d", max, num)
+			if num != int64(tt.max) {
+				t.Errorf("num want: %d, got: %d", tt.max, num)
 			}
 
 			if err := tx.Rollback(); err != nil {
@@ -309,3 +327,23 @@ func TestBulkInsertTxContext(t *testing.T) {
 		})
 	}
 }
+
+func TestNamedExecBatches(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "batches"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A row has more bound variables than the limit of SQLite. (32766)
+	query := "INSERT INTO users (name, email) VALUES (" + strings.Repeat(":name || ", 32766) + ":name, '')"
+	rows := []*User{{Name: "a"}, {Name: "b"}}
+	if _, err := dbutil.ExportNamedExecBatches(ctx, db, query, rows); err == nil {
+		t.Error("want: error of too many bound variables, got: nil")
+	}
+}
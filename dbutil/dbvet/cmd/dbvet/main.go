@@ -0,0 +1,14 @@
+// dbvet checks queries of dbutil helpers.
+//
+//	go build -o dbvet ./dbutil/dbvet/cmd/dbvet
+//	go vet -vettool=$(pwd)/dbvet ./...
+package main
+
+import (
+	"github.com/exaream/go-db/dbutil/dbvet"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(dbvet.Analyzer)
+}
@@ -0,0 +1,349 @@
+// Package dbvet provides an analyzer which checks queries of dbutil helpers at compile time.
+//
+// It reports named parameters which have no args, args which are not used by the query,
+// and selected columns which can not be scanned into the struct.
+// Queries which are not constants, such as ones loaded by dbutil.LoadQueries, are not checked.
+package dbvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const dbutilPath = "github.com/exaream/go-db/dbutil"
+
+// helper is positions of arguments of a dbutil helper.
+type helper struct {
+	query  int
+	args   int  // -1 if the args are structs of T
+	scan   bool // whether results are scanned into T
+	fields bool // whether named parameters are fields of T
+}
+
+// Helpers which are checked
+var helpers = map[string]helper{
+	"SelectContext":       {query: 2, args: 3, scan: true},
+	"GetContext":          {query: 2, args: 3, scan: true},
+	"SelectTxContext":     {query: 2, args: 3, scan: true},
+	"UpdateTxContext":     {query: 2, args: 3},
+	"UpdateRowTxContext":  {query: 2, args: -1, fields: true},
+	"BulkInsertTxContext": {query: 3, args: -1, fields: true},
+}
+
+// Analyzer checks named parameters, args and selected columns of dbutil helpers.
+var Analyzer = &analysis.Analyzer{
+	Name:     "dbvet",
+	Doc:      "check named parameters, args and selected columns of dbutil helpers",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	maps := collectMaps(pass, ins)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name, typ := helperFunc(pass, call)
+		h, ok := helpers[name]
+		if !ok || len(call.Args) <= h.query {
+			return
+		}
+
+		queryExpr := call.Args[h.query]
+		tv, ok := pass.TypesInfo.Types[queryExpr]
+		if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+			return
+		}
+		query := constant.StringVal(tv.Value)
+
+		params, err := NamedParams(query)
+		if err != nil {
+			pass.Reportf(queryExpr.Pos(), "invalid named query: %v", err)
+			return
+		}
+
+		if h.args >= 0 && len(call.Args) > h.args {
+			checkArgs(pass, maps, call.Args[h.args], call.Pos(), params)
+		}
+		if typ == nil {
+			return
+		}
+		fields := fieldNames(typ)
+		if h.fields {
+			for _, p := range params {
+				if !fields[p] {
+					pass.Reportf(queryExpr.Pos(), "named parameter :%s is not a field of %s", p, types.TypeString(typ, types.RelativeTo(pass.Pkg)))
+				}
+			}
+		}
+		if h.scan {
+			for _, col := range SelectColumns(query) {
+				if !fields[col] {
+					pass.Reportf(queryExpr.Pos(), "column %s can not be scanned into %s", col, types.TypeString(typ, types.RelativeTo(pass.Pkg)))
+				}
+			}
+		}
+	})
+
+	return nil, nil
+}
+
+// helperFunc returns the name of the dbutil function which is called and its type argument.
+func helperFunc(pass *analysis.Pass, call *ast.CallExpr) (string, types.Type) {
+	fun := astutil.Unparen(call.Fun)
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+
+	var id *ast.Ident
+	switch f := fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return "", nil
+	}
+
+	fn, ok := pass.TypesInfo.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != dbutilPath {
+		return "", nil
+	}
+
+	var typ types.Type
+	if inst, ok := pass.TypesInfo.Instances[id]; ok && inst.TypeArgs.Len() > 0 {
+		typ = inst.TypeArgs.At(0)
+	}
+	return fn.Name(), typ
+}
+
+// mapEvent is an assignment to a variable of args.
+type mapEvent struct {
+	pos   token.Pos
+	reset bool            // whether the variable is assigned a new map
+	keys  map[string]bool // constant keys
+	exprs map[string]ast.Expr
+	known bool // whether all keys are constants
+}
+
+// collectMaps returns assignments to variables of maps in order of positions.
+func collectMaps(pass *analysis.Pass, ins *inspector.Inspector) map[types.Object][]mapEvent {
+	maps := make(map[types.Object][]mapEvent)
+	ins.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.ValueSpec)(nil)}, func(n ast.Node) {
+		var lhs, rhs []ast.Expr
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			lhs, rhs = s.Lhs, s.Rhs
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				lhs = append(lhs, name)
+			}
+			rhs = s.Values
+		}
+		if len(lhs) != len(rhs) {
+			return
+		}
+
+		for i, l := range lhs {
+			switch l := l.(type) {
+			case *ast.Ident:
+				obj := objectOf(pass, l)
+				if obj == nil {
+					continue
+				}
+				if _, ok := obj.Type().Underlying().(*types.Map); !ok {
+					continue
+				}
+				ev := mapEvent{pos: n.Pos(), reset: true}
+				ev.keys, ev.exprs, ev.known = mapKeys(pass, rhs[i])
+				maps[obj] = append(maps[obj], ev)
+			case *ast.IndexExpr:
+				id, ok := astutil.Unparen(l.X).(*ast.Ident)
+				if !ok {
+					continue
+				}
+				obj := objectOf(pass, id)
+				if obj == nil {
+					continue
+				}
+				ev := mapEvent{pos: n.Pos(), keys: map[string]bool{}, exprs: map[string]ast.Expr{}, known: true}
+				if key, ok := stringConst(pass, l.Index); ok {
+					ev.keys[key] = true
+				} else {
+					ev.known = false
+				}
+				maps[obj] = append(maps[obj], ev)
+			}
+		}
+	})
+	return maps
+}
+
+// objectOf returns the variable of the identifier.
+func objectOf(pass *analysis.Pass, id *ast.Ident) types.Object {
+	if obj := pass.TypesInfo.Defs[id]; obj != nil {
+		return obj
+	}
+	return pass.TypesInfo.Uses[id]
+}
+
+// mapKeys returns constant keys of a map literal.
+// known is false if the expression is not a map literal or has keys which are not constants.
+func mapKeys(pass *analysis.Pass, expr ast.Expr) (keys map[string]bool, exprs map[string]ast.Expr, known bool) {
+	keys, exprs = map[string]bool{}, map[string]ast.Expr{}
+	expr = astutil.Unparen(expr)
+	if tv, ok := pass.TypesInfo.Types[expr]; ok && tv.IsNil() {
+		return keys, exprs, true
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, nil, false
+	}
+	if _, ok := pass.TypesInfo.TypeOf(lit).Underlying().(*types.Map); !ok {
+		return nil, nil, false
+	}
+
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, nil, false
+		}
+		key, ok := stringConst(pass, kv.Key)
+		if !ok {
+			return nil, nil, false
+		}
+		keys[key] = true
+		exprs[key] = kv.Key
+	}
+	return keys, exprs, true
+}
+
+// stringConst returns the value of a string constant.
+func stringConst(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// checkArgs reports named parameters which have no args and args which are not used.
+func checkArgs(pass *analysis.Pass, maps map[types.Object][]mapEvent, expr ast.Expr, pos token.Pos, params []string) {
+	keys, exprs, known := mapKeys(pass, expr)
+	if !known {
+		id, ok := astutil.Unparen(expr).(*ast.Ident)
+		if !ok {
+			return
+		}
+		keys, exprs, known = keysAt(maps[objectOf(pass, id)], pos)
+		if !known {
+			return
+		}
+	}
+
+	used := make(map[string]bool, len(params))
+	for _, p := range params {
+		used[p] = true
+		if !keys[p] {
+			pass.Reportf(expr.Pos(), "named parameter :%s has no arg", p)
+		}
+	}
+
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		if used[key] {
+			continue
+		}
+		at := expr.Pos()
+		if e, ok := exprs[key]; ok {
+			at = e.Pos()
+		}
+		pass.Reportf(at, "arg %s is not used by the query", strconv.Quote(key))
+	}
+}
+
+// keysAt returns keys of the map variable at the position.
+// known is false if the variable has not been assigned a map literal before the position.
+func keysAt(events []mapEvent, pos token.Pos) (keys map[string]bool, exprs map[string]ast.Expr, known bool) {
+	for _, ev := range events {
+		if ev.pos >= pos {
+			break
+		}
+		if ev.reset {
+			keys, exprs, known = map[string]bool{}, map[string]ast.Expr{}, ev.known
+		}
+		if keys == nil {
+			continue
+		}
+		for key := range ev.keys {
+			keys[key] = true
+		}
+		for key, e := range ev.exprs {
+			exprs[key] = e
+		}
+		known = known && ev.known
+	}
+	return keys, exprs, known && keys != nil
+}
+
+// fieldNames returns names of columns which sqlx maps to fields of the struct.
+// Fields are named by db tags, or lower cased field names without tags.
+func fieldNames(typ types.Type) map[string]bool {
+	names := make(map[string]bool)
+	addFields(names, typ, "", map[types.Type]bool{})
+	return names
+}
+
+// addFields adds names of fields of the struct with the prefix.
+func addFields(names map[string]bool, typ types.Type, prefix string, seen map[types.Type]bool) {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok || seen[typ] {
+		return
+	}
+	seen[typ] = true
+	defer delete(seen, typ)
+
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() && !f.Embedded() {
+			continue
+		}
+
+		tag := reflect.StructTag(st.Tag(i)).Get("db")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if f.Embedded() && name == "" {
+			addFields(names, f.Type(), prefix, seen)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name())
+		}
+		names[prefix+name] = true
+		addFields(names, f.Type(), prefix+name+".", seen)
+	}
+}
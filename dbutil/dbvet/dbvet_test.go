@@ -0,0 +1,148 @@
+package dbvet_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/exaream/go-db/dbutil/dbvet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// dbutilPath is the import path of dbutil, whose stub is in test data.
+const dbutilPath = "github.com/exaream/go-db/dbutil"
+
+// extRegexp is a pattern of types of other packages which the stub replaces with any.
+var extRegexp = regexp.MustCompile(`\bsqlx\.\w+`)
+
+func TestAnalyzer(t *testing.T) {
+	t.Parallel()
+
+	analysistest.Run(t, analysistest.TestData(), dbvet.Analyzer, "a")
+}
+
+func TestNamedParams(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		"params": {`UPDATE users SET status = :afterSts WHERE id = :id AND status = :beforeSts`, []string{"afterSts", "id", "beforeSts"}, false},
+		"values": {`INSERT INTO users (name) VALUES (:name),(:u.name)`, []string{"name", "u.name"}, false},
+		"cast":   {`SELECT 'a'::text`, nil, false},
+		"assign": {`SELECT @a := 1`, nil, false},
+		"error":  {`SELECT :id::text`, nil, true},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := dbvet.NamedParams(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error want: %t, got: %v", tt.wantErr, err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("want: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query string
+		want  []string
+	}{
+		"columns":  {`SELECT id, name FROM users`, []string{"id", "name"}},
+		"qualify":  {"SELECT u.id, `u`.`name`, \"u\".\"email\" FROM users u", []string{"id", "name", "email"}},
+		"alias":    {`select distinct COUNT(id) AS num, MAX(status) max_status, CASE WHEN id = 1 THEN 1 END from users`, []string{"num", "max_status", "CASE WHEN id = 1 THEN 1 END"}},
+		"function": {`SELECT COALESCE(name, 'from') AS name, NOW()`, []string{"name", "NOW()"}},
+		"star":     {`SELECT u.* FROM users u`, nil},
+		"update":   {`UPDATE users SET status = 1`, nil},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := dbvet.SelectColumns(tt.query)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("want: %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestStubs checks that functions of the stub of dbutil in test data have the same signatures as dbutil,
+// except that types of sqlx such as sqlx.ExtContext are any.
+func TestStubs(t *testing.T) {
+	t.Parallel()
+
+	decls := func(pattern string) map[string]string {
+		t.Helper()
+
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fset := token.NewFileSet()
+		sigs := make(map[string]string)
+		for _, p := range paths {
+			if strings.HasSuffix(p, "_test.go") {
+				continue
+			}
+			f, err := parser.ParseFile(fset, p, nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, decl := range f.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || !fn.Name.IsExported() {
+					continue
+				}
+				name := fn.Name.Name
+				if fn.Recv != nil {
+					name = types.ExprString(fn.Recv.List[0].Type) + "." + name
+				}
+				// ExprString omits type parameters.
+				var tparams []string
+				if fn.Type.TypeParams != nil {
+					for _, field := range fn.Type.TypeParams.List {
+						for _, ident := range field.Names {
+							tparams = append(tparams, ident.Name+" "+types.ExprString(field.Type))
+						}
+					}
+				}
+				sig := fmt.Sprintf("[%s]%s", strings.Join(tparams, ", "), types.ExprString(fn.Type))
+				sigs[name] = extRegexp.ReplaceAllString(sig, "any")
+			}
+		}
+		return sigs
+	}
+
+	stubs := decls(filepath.Join("testdata", "src", filepath.FromSlash(dbutilPath), "*.go"))
+	funcs := decls(filepath.Join("..", "*.go"))
+	if len(stubs) == 0 {
+		t.Fatal("want: functions of the stub, got: none")
+	}
+	for name, stub := range stubs {
+		if got, ok := funcs[name]; !ok {
+			t.Errorf("%s of the stub is not found in dbutil", name)
+		} else if got != stub {
+			t.Errorf("%s of the stub differs from dbutil\nstub:   %s\ndbutil: %s", name, stub, got)
+		}
+	}
+}
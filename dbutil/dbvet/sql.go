@@ -0,0 +1,141 @@
+package dbvet
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Patterns of selected columns
+var (
+	// e.g. COUNT(*) AS num, u.name "name"
+	aliasRegexp = regexp.MustCompile("(?is)^.*\\S\\s+(?:AS\\s+)?([A-Za-z_]\\w*|\"[^\"]+\"|`[^`]+`)$")
+	// e.g. id, u.name, "users"."name"
+	columnRegexp = regexp.MustCompile("^(?:(?:\\w+|\"[^\"]+\"|`[^`]+`)\\.)*(\\w+|\"[^\"]+\"|`[^`]+`)$")
+)
+
+// Words which end expressions rather than alias them
+var notAliases = map[string]bool{"END": true, "NULL": true, "TRUE": true, "FALSE": true}
+
+// NamedParams returns named parameters of the query in the same way as sqlx.
+// "::" is an escaped colon such as casts of PostgreSQL, and ":=" is not a parameter.
+func NamedParams(query string) ([]string, error) {
+	var params []string
+	var name []rune
+	inName := false
+
+	runes := []rune(query)
+	for i, r := range runes {
+		switch {
+		case r == ':' && inName && i > 0 && runes[i-1] == ':':
+			inName = false
+		case r == ':' && inName:
+			return nil, fmt.Errorf("unexpected `:` while reading named param at %d", i)
+		case r == ':':
+			inName = true
+			name = name[:0]
+		case inName && r == '=' && len(name) == 0:
+			inName = false
+		case inName && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'):
+			name = append(name, r)
+		case inName:
+			inName = false
+			params = append(params, string(name))
+		}
+	}
+	if inName {
+		params = append(params, string(name))
+	}
+
+	return params, nil
+}
+
+// SelectColumns returns names of columns selected by the query.
+// It returns nil if the query is not SELECT or the names are unknown such as "*".
+// Columns of expressions without aliases are named as the expressions.
+func SelectColumns(query string) []string {
+	query = strings.TrimSpace(query)
+	if len(query) < len("SELECT ") || !strings.EqualFold(query[:len("SELECT")], "SELECT") || !unicode.IsSpace(rune(query[len("SELECT")])) {
+		return nil
+	}
+
+	items := selectList(query[len("SELECT"):])
+	if len(items) > 0 {
+		first := strings.Fields(items[0])
+		if len(first) > 1 && (strings.EqualFold(first[0], "DISTINCT") || strings.EqualFold(first[0], "ALL")) {
+			items[0] = strings.TrimSpace(items[0][strings.Index(items[0], first[0])+len(first[0]):])
+		}
+	}
+
+	columns := make([]string, 0, len(items))
+	for _, item := range items {
+		if strings.HasSuffix(item, "*") {
+			return nil
+		}
+		columns = append(columns, columnName(item))
+	}
+	return columns
+}
+
+// selectList returns items of the select list, which ends with FROM or the end of the query.
+func selectList(s string) []string {
+	var items []string
+	var quote rune
+	depth, start := 0, 0
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth > 0:
+		case r == ',':
+			items = append(items, strings.TrimSpace(string(runes[start:i])))
+			start = i + 1
+		case r == ';' || isKeywordAt(runes, i, "FROM"):
+			return append(items, strings.TrimSpace(string(runes[start:i])))
+		}
+	}
+	return append(items, strings.TrimSpace(string(runes[start:])))
+}
+
+// isKeywordAt reports whether the keyword is at the position as a word.
+func isKeywordAt(runes []rune, i int, keyword string) bool {
+	if i > 0 && !unicode.IsSpace(runes[i-1]) && runes[i-1] != ')' {
+		return false
+	}
+	end := i + len(keyword)
+	if end > len(runes) || !strings.EqualFold(string(runes[i:end]), keyword) {
+		return false
+	}
+	return end == len(runes) || unicode.IsSpace(runes[end]) || runes[end] == '('
+}
+
+// columnName returns the name of the column of an item of the select list.
+func columnName(item string) string {
+	if m := columnRegexp.FindStringSubmatch(item); m != nil {
+		return unquote(m[1])
+	}
+	if m := aliasRegexp.FindStringSubmatch(item); m != nil && !notAliases[strings.ToUpper(m[1])] {
+		return unquote(m[1])
+	}
+	return item
+}
+
+// unquote removes quotes of an identifier.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '`') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package a
+
+import (
+	"context"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+const (
+	querySelect     = `SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status;`
+	querySelectJoin = `SELECT u.id, u.name AS name, COUNT(*) AS num, MAX(u.status) FROM users u WHERE u.id = :id GROUP BY u.id, u.name;`
+	querySelectAll  = `SELECT * FROM users WHERE id = :id;`
+	queryUpdate     = `UPDATE users SET status = :afterSts, updated_at = CURRENT_TIMESTAMP WHERE id = :id AND status = :beforeSts;`
+	queryUpdateRow  = `UPDATE users SET name = :name, updated_at = :updated_at WHERE id = :id;`
+	queryInsert     = `INSERT INTO users (name, email, status) VALUES (:name, :email, :status);`
+	queryCast       = `SELECT id FROM users WHERE name = :name::text;`
+)
+
+type Base struct {
+	ID int `db:"id"`
+}
+
+type User struct {
+	Base
+	Name      string     `db:"name"`
+	Email     string     `db:"-"`
+	Status    int        // status
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at,autoupdate"`
+}
+
+func run(ctx context.Context, db any, tx dbutil.Transaction, q *dbutil.Queries, params map[string]any) {
+	_, _ = dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": 1})
+	_, _ = dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1}) // want `named parameter :status has no arg`
+	_, _ = dbutil.GetContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": 1, "name": "x"}) // want `arg "name" is not used by the query`
+	_, _ = dbutil.SelectContext[User](ctx, db, querySelectJoin, map[string]any{"id": 1}) // want `column num can not be scanned into User` `column MAX\(u.status\) can not be scanned into User`
+	_, _ = dbutil.SelectContext[User](ctx, db, querySelectAll, map[string]any{"id": 1})
+	_, _ = dbutil.SelectContext[User](ctx, db, querySelectAll, nil) // want `named parameter :id has no arg`
+	_, _ = dbutil.SelectContext[User](ctx, db, queryCast, params)   // want `invalid named query: unexpected .*`
+	_, _ = dbutil.SelectContext[User](ctx, db, q.MustQuery("SelectUser", "mysql"), nil)
+
+	args := map[string]any{"id": 1, "beforeSts": 0}
+	_, _ = dbutil.UpdateTxContext(ctx, tx, queryUpdate, args) // want `named parameter :afterSts has no arg`
+	args["afterSts"] = 1
+	_, _ = dbutil.UpdateTxContext(ctx, tx, queryUpdate, args)
+	_, _ = dbutil.UpdateTxContext(ctx, tx, queryUpdate, params)
+
+	_, _ = dbutil.UpdateRowTxContext(ctx, tx, queryUpdateRow, &User{})
+	_, _ = dbutil.UpdateRowTxContext(ctx, tx, queryInsert, &User{}) // want `named parameter :email is not a field of User`
+
+	_, _ = dbutil.BulkInsertTxContext(ctx, tx, func(i, j int) []*User { return nil }, queryInsert, 1, 10, 5) // want `named parameter :email is not a field of User`
+}
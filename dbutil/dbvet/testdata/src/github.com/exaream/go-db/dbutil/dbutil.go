@@ -0,0 +1,39 @@
+// Package dbutil is a stub of the helpers for tests of dbvet.
+package dbutil
+
+import "context"
+
+type Query string
+
+type Transaction interface{}
+
+func SelectContext[T any](ctx context.Context, db any, query Query, args map[string]any) ([]*T, error) {
+	return nil, nil
+}
+
+func GetContext[T any](ctx context.Context, db any, query Query, args map[string]any) (*T, error) {
+	return nil, nil
+}
+
+func SelectTxContext[T any](ctx context.Context, tx Transaction, query Query, args map[string]any) ([]*T, error) {
+	return nil, nil
+}
+
+func UpdateTxContext(ctx context.Context, tx Transaction, query Query, args map[string]any) (int64, error) {
+	return 0, nil
+}
+
+func UpdateRowTxContext[T any](ctx context.Context, tx Transaction, query Query, row *T) (int64, error) {
+	return 0, nil
+}
+
+func BulkInsertTxContext[T any](ctx context.Context, tx Transaction,
+	fn func(i, j int) []*T, query Query, min, max, chunkSize int) (int64, error) {
+	return 0, nil
+}
+
+type Queries struct{}
+
+func (q *Queries) MustQuery(name, driver string) Query {
+	return ""
+}
@@ -0,0 +1,330 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect is differences of SQL between MySQL, PostgreSQL and SQLite.
+// Identifiers given to its methods are quoted, and values are named parameters of the column names.
+type Dialect interface {
+	// Driver returns the driver name of the dialect.
+	Driver() string
+	// Placeholder returns the n-th (1-based) positional placeholder. e.g. "?", "$1"
+	// It is for queries run by database/sql directly because the helpers and Builder use named parameters.
+	Placeholder(n int) string
+	// QuoteIdent returns the identifier quoted. Dots separate qualifiers. e.g. "u.name"
+	QuoteIdent(name string) (string, error)
+	// TruncateTable returns a statement which deletes all rows of the table and resets its identity.
+	// The statement of SQLite does not reset the sequence of AUTOINCREMENT, which TruncateTableContext does.
+	TruncateTable(table string) (string, error)
+	// LimitOffset returns LIMIT and OFFSET clause. limit <= 0 means no limit.
+	LimitOffset(limit, offset int) string
+	// Upsert returns INSERT of the columns which updates the other columns if the keys conflict.
+	Upsert(table string, columns, keys []string) (string, error)
+	// SupportsReturning reports whether RETURNING clause is supported.
+	SupportsReturning() bool
+	// CurrentTimestamp returns an expression of the current date and time.
+	CurrentTimestamp() string
+}
+
+// Dialects by drivers
+var dialects = map[string]Dialect{
+	mysqlDriver:  mysqlDialect{},
+	pgsqlDriver:  pgsqlDialect{},
+	sqliteDriver: sqliteDialect{},
+}
+
+// DialectOf returns Dialect of the driver such as db.DriverName().
+func DialectOf(driver string) (Dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %s", driver)
+	}
+	return d, nil
+}
+
+// TruncateTableContext deletes all rows of the table and resets its identity by the dialect of the driver of db.
+// For SQLite, the sequence of AUTOINCREMENT is deleted from sqlite_sequence if it exists.
+func TruncateTableContext(ctx context.Context, db sqlx.ExtContext, table string) error {
+	d, err := DialectOf(db.DriverName())
+	if err != nil {
+		return err
+	}
+	query, err := d.TruncateTable(table)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if d.Driver() != sqliteDriver {
+		return nil
+	}
+	return resetSQLiteSequence(ctx, db, table)
+}
+
+// resetSQLiteSequence deletes the sequence of AUTOINCREMENT of the table.
+// sqlite_sequence is in the schema of the table, has the name without the schema,
+// and exists only after a table of AUTOINCREMENT is created.
+func resetSQLiteSequence(ctx context.Context, db sqlx.ExtContext, table string) error {
+	schema, name := "", table
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		s, err := quoteIdent(`"`, table[:i])
+		if err != nil {
+			return err
+		}
+		schema, name = s+".", table[i+1:]
+	}
+
+	var num int
+	query := "SELECT COUNT(*) FROM " + schema + "sqlite_master WHERE type = 'table' AND name = 'sqlite_sequence'"
+	if err := sqlx.GetContext(ctx, db, &num, query); err != nil {
+		return err
+	}
+	if num == 0 {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, "DELETE FROM "+schema+"sqlite_sequence WHERE name = ?", name)
+	return err
+}
+
+// quoteIdent returns the identifier quoted by the quote character.
+func quoteIdent(quote, name string) (string, error) {
+	if name == "" || strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("invalid identifier %q", name)
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("invalid identifier %q", name)
+		}
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// quoteIdents returns the identifiers quoted by the dialect.
+func quoteIdents(d Dialect, names []string) ([]string, error) {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		q, err := d.QuoteIdent(name)
+		if err != nil {
+			return nil, err
+		}
+		quoted[i] = q
+	}
+	return quoted, nil
+}
+
+// insertQuery returns INSERT of the columns whose values are named parameters of the column names.
+func insertQuery(d Dialect, table string, columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("no columns to insert into %s", table)
+	}
+
+	t, err := d.QuoteIdent(table)
+	if err != nil {
+		return "", err
+	}
+	cols, err := quoteIdents(d, columns)
+	if err != nil {
+		return "", err
+	}
+	params := make([]string, len(columns))
+	for i, col := range columns {
+		params[i] = ":" + col
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t, strings.Join(cols, ", "), strings.Join(params, ", ")), nil
+}
+
+// updateColumns returns columns which are not keys.
+func updateColumns(columns, keys []string) []string {
+	isKey := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	var cols []string
+	for _, col := range columns {
+		if !isKey[col] {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// upsertOnConflict returns upsert by ON CONFLICT clause of PostgreSQL and SQLite.
+func upsertOnConflict(d Dialect, table string, columns, keys []string) (string, error) {
+	insert, err := insertQuery(d, table, columns)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("no conflict keys of %s", table)
+	}
+	quotedKeys, err := quoteIdents(d, keys)
+	if err != nil {
+		return "", err
+	}
+
+	cols, err := quoteIdents(d, updateColumns(columns, keys))
+	if err != nil {
+		return "", err
+	}
+	if len(cols) == 0 {
+		return fmt.Sprintf("%s ON CONFLICT (%s) DO NOTHING", insert, strings.Join(quotedKeys, ", ")), nil
+	}
+	sets := make([]string, len(cols))
+	for i, col := range cols {
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", insert, strings.Join(quotedKeys, ", "), strings.Join(sets, ", ")), nil
+}
+
+// mysqlDialect is Dialect of MySQL.
+type mysqlDialect struct{}
+
+func (mysqlDialect) Driver() string { return mysqlDriver }
+
+func (mysqlDialect) Placeholder(int) string { return "?" }
+
+func (mysqlDialect) QuoteIdent(name string) (string, error) { return quoteIdent("`", name) }
+
+func (d mysqlDialect) TruncateTable(table string) (string, error) {
+	t, err := d.QuoteIdent(table)
+	if err != nil {
+		return "", err
+	}
+	return "TRUNCATE TABLE " + t, nil
+}
+
+// LimitOffset uses the maximum of LIMIT because MySQL has no OFFSET without LIMIT.
+func (mysqlDialect) LimitOffset(limit, offset int) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+	clause := "LIMIT 18446744073709551615"
+	if limit > 0 {
+		clause = "LIMIT " + strconv.Itoa(limit)
+	}
+	if offset > 0 {
+		clause += " OFFSET " + strconv.Itoa(offset)
+	}
+	return clause
+}
+
+// Upsert uses ON DUPLICATE KEY UPDATE, which is triggered by any unique keys of the table.
+// The new row is referred by the row alias of MySQL 8.0.19 or later because VALUES() is deprecated.
+func (d mysqlDialect) Upsert(table string, columns, keys []string) (string, error) {
+	insert, err := insertQuery(d, table, columns)
+	if err != nil {
+		return "", err
+	}
+
+	cols := updateColumns(columns, keys)
+	if len(cols) == 0 {
+		cols = keys
+	}
+	quoted, err := quoteIdents(d, cols)
+	if err != nil {
+		return "", err
+	}
+	if len(quoted) == 0 {
+		return "", fmt.Errorf("no conflict keys of %s", table)
+	}
+	sets := make([]string, len(quoted))
+	for i, col := range quoted {
+		sets[i] = fmt.Sprintf("%s = new.%s", col, col)
+	}
+	return fmt.Sprintf("%s AS new ON DUPLICATE KEY UPDATE %s", insert, strings.Join(sets, ", ")), nil
+}
+
+func (mysqlDialect) SupportsReturning() bool { return false }
+
+func (mysqlDialect) CurrentTimestamp() string { return "NOW()" }
+
+// pgsqlDialect is Dialect of PostgreSQL.
+type pgsqlDialect struct{}
+
+func (pgsqlDialect) Driver() string { return pgsqlDriver }
+
+func (pgsqlDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (pgsqlDialect) QuoteIdent(name string) (string, error) { return quoteIdent(`"`, name) }
+
+func (d pgsqlDialect) TruncateTable(table string) (string, error) {
+	t, err := d.QuoteIdent(table)
+	if err != nil {
+		return "", err
+	}
+	return "TRUNCATE TABLE " + t + " RESTART IDENTITY", nil
+}
+
+func (pgsqlDialect) LimitOffset(limit, offset int) string {
+	var clauses []string
+	if limit > 0 {
+		clauses = append(clauses, "LIMIT "+strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		clauses = append(clauses, "OFFSET "+strconv.Itoa(offset))
+	}
+	return strings.Join(clauses, " ")
+}
+
+func (d pgsqlDialect) Upsert(table string, columns, keys []string) (string, error) {
+	return upsertOnConflict(d, table, columns, keys)
+}
+
+func (pgsqlDialect) SupportsReturning() bool { return true }
+
+func (pgsqlDialect) CurrentTimestamp() string { return "NOW()" }
+
+// sqliteDialect is Dialect of SQLite.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Driver() string { return sqliteDriver }
+
+func (sqliteDialect) Placeholder(int) string { return "?" }
+
+func (sqliteDialect) QuoteIdent(name string) (string, error) { return quoteIdent(`"`, name) }
+
+// TruncateTable uses DELETE because SQLite has no TRUNCATE.
+func (d sqliteDialect) TruncateTable(table string) (string, error) {
+	t, err := d.QuoteIdent(table)
+	if err != nil {
+		return "", err
+	}
+	return "DELETE FROM " + t, nil
+}
+
+// LimitOffset uses LIMIT -1 because SQLite has no OFFSET without LIMIT.
+func (sqliteDialect) LimitOffset(limit, offset int) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+	clause := "LIMIT -1"
+	if limit > 0 {
+		clause = "LIMIT " + strconv.Itoa(limit)
+	}
+	if offset > 0 {
+		clause += " OFFSET " + strconv.Itoa(offset)
+	}
+	return clause
+}
+
+func (d sqliteDialect) Upsert(table string, columns, keys []string) (string, error) {
+	return upsertOnConflict(d, table, columns, keys)
+}
+
+func (sqliteDialect) SupportsReturning() bool { return true }
+
+func (sqliteDialect) CurrentTimestamp() string { return "CURRENT_TIMESTAMP" }
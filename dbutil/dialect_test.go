@@ -0,0 +1,167 @@
+package dbutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+func TestDialect(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		driver      string
+		placeholder string
+		ident       string
+		quoted      string
+		truncate    string
+		limit       string
+		offset      string
+		upsert      string
+		returning   bool
+		now         string
+	}{
+		"mysql": {
+			mysqlDriver, "?", "u.na`me", "`u`.`na``me`",
+			"TRUNCATE TABLE `users`",
+			"LIMIT 10 OFFSET 20", "LIMIT 18446744073709551615 OFFSET 20",
+			"INSERT INTO `users` (`id`, `name`) VALUES (:id, :name) AS new ON DUPLICATE KEY UPDATE `name` = new.`name`",
+			false, "NOW()",
+		},
+		"pgsql": {
+			pgsqlDriver, "$2", `u.na"me`, `"u"."na""me"`,
+			`TRUNCATE TABLE "users" RESTART IDENTITY`,
+			"LIMIT 10 OFFSET 20", "OFFSET 20",
+			`INSERT INTO "users" ("id", "name") VALUES (:id, :name) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+			true, "NOW()",
+		},
+		"sqlite": {
+			sqliteDriver, "?", `u.na"me`, `"u"."na""me"`,
+			`DELETE FROM "users"`,
+			"LIMIT 10 OFFSET 20", "LIMIT -1 OFFSET 20",
+			`INSERT INTO "users" ("id", "name") VALUES (:id, :name) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+			true, "CURRENT_TIMESTAMP",
+		},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d, err := dbutil.DialectOf(tt.driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := d.Driver(); got != tt.driver {
+				t.Errorf("driver want: %s, got: %s", tt.driver, got)
+			}
+			if got := d.Placeholder(2); got != tt.placeholder {
+				t.Errorf("placeholder want: %s, got: %s", tt.placeholder, got)
+			}
+			if got, err := d.QuoteIdent(tt.ident); err != nil || got != tt.quoted {
+				t.Errorf("quoted want: %s, got: %s, %v", tt.quoted, got, err)
+			}
+			if got, err := d.TruncateTable("users"); err != nil || got != tt.truncate {
+				t.Errorf("truncate want: %s, got: %s, %v", tt.truncate, got, err)
+			}
+			if got := d.LimitOffset(10, 20); got != tt.limit {
+				t.Errorf("limit want: %s, got: %s", tt.limit, got)
+			}
+			if got := d.LimitOffset(0, 20); got != tt.offset {
+				t.Errorf("offset want: %s, got: %s", tt.offset, got)
+			}
+			if got := d.LimitOffset(0, 0); got != "" {
+				t.Errorf("no limit want: empty, got: %s", got)
+			}
+			if got, err := d.Upsert("users", []string{"id", "name"}, []string{"id"}); err != nil || got != tt.upsert {
+				t.Errorf("upsert want: %s, got: %s, %v", tt.upsert, got, err)
+			}
+			if got := d.SupportsReturning(); got != tt.returning {
+				t.Errorf("returning want: %t, got: %t", tt.returning, got)
+			}
+			if got := d.CurrentTimestamp(); got != tt.now {
+				t.Errorf("current timestamp want: %s, got: %s", tt.now, got)
+			}
+
+			if _, err := d.QuoteIdent("u."); err == nil {
+				t.Error("want: error of an empty identifier, got: nil")
+			}
+			if _, err := d.Upsert("users", nil, []string{"id"}); err == nil {
+				t.Error("want: error of no columns, got: nil")
+			}
+		})
+	}
+
+	if _, err := dbutil.DialectOf("dummy"); err == nil {
+		t.Error("want: error of an unknown driver, got: nil")
+	}
+}
+
+func TestDialectSQLite(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "dialect"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	d, err := dbutil.DialectOf(db.DriverName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upsert, err := d.Upsert("users", []string{"id", "name", "email", "status"}, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NamedExecContext(ctx, upsert, map[string]any{"id": 1, "name": "upserted", "email": "", "status": non}); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := db.GetContext(ctx, &name, "SELECT name FROM users WHERE id = 1 AND "+d.CurrentTimestamp()+" IS NOT NULL"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "upserted" {
+		t.Errorf("name want: upserted, got: %s", name)
+	}
+
+	// sqlite_sequence does not exist because users has no AUTOINCREMENT.
+	if err := dbutil.TruncateTableContext(ctx, db, "users"); err != nil {
+		t.Fatal(err)
+	}
+	var num int
+	if err := db.GetContext(ctx, &num, "SELECT COUNT(*) FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	if num != 0 {
+		t.Errorf("rows want: 0, got: %d", num)
+	}
+
+	// The sequence of AUTOINCREMENT restarts.
+	if _, err := db.ExecContext(ctx, "CREATE TABLE seqs (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := db.ExecContext(ctx, "INSERT INTO seqs (name) VALUES ('a')"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dbutil.TruncateTableContext(ctx, db, "main.seqs"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := db.ExecContext(ctx, "INSERT INTO seqs (name) VALUES ('a')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := res.LastInsertId(); err != nil || id != 1 {
+		t.Errorf("id want: 1, got: %d, %v", id, err)
+	}
+}
@@ -1,4 +1,19 @@
 package dbutil
 
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
 var ExportDataSrcMySQL = (*Config).dataSrcMySQL
 var ExportDataSrcPgSQL = (*Config).dataSrcPgSQL
+var ExportDataSrcSQLite = (*Config).dataSrcSQLite
+var ExportSessionStatements = (*Config).sessionStatements
+var ExportIsPermanent = isPermanent
+var ExportSplitStatements = splitStatements
+var ExportEject = (*Cluster).eject
+
+func ExportNamedExecBatches[T any](ctx context.Context, db sqlx.ExtContext, query string, rows []*T) (int64, error) {
+	return namedExecBatches(ctx, db, query, rows)
+}
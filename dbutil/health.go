@@ -0,0 +1,187 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/multierr"
+)
+
+const (
+	defaultProbeQuery   = "SELECT 1"
+	defaultProbeTimeout = 3 * time.Second
+)
+
+// Replication roles of Health
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// Queries of server facts: version, read-only state and whether it is a replica.
+// The replication role of MySQL is checked by mysqlReplicaQueries instead.
+var serverFactsQueries = map[string]string{
+	mysqlDriver:  `SELECT VERSION(), @@global.read_only, 0;`,
+	pgsqlDriver:  `SELECT current_setting('server_version'), current_setting('default_transaction_read_only') = 'on' OR pg_is_in_recovery(), pg_is_in_recovery();`,
+	sqliteDriver: `SELECT sqlite_version(), 0, 0;`,
+}
+
+// Queries which return rows if MySQL is a replica, in order to try.
+// Read-only state is not the role because primaries can be read-only on failover.
+// performance_schema needs SELECT on it, and SHOW REPLICA STATUS needs REPLICATION CLIENT.
+var mysqlReplicaQueries = []string{
+	`SELECT CHANNEL_NAME FROM performance_schema.replication_connection_status;`,
+	`SHOW REPLICA STATUS;`,
+	`SHOW SLAVE STATUS;`, // before MySQL 8.0.22 and MariaDB 10.5.1
+}
+
+// HealthChecker checks health of DB handle.
+// Zero values are replaced with defaults.
+type HealthChecker struct {
+	DB         *sqlx.DB
+	ProbeQuery string        // query run by Readiness and Check, "SELECT 1" by default
+	Timeout    time.Duration // timeout of each check, 3s by default
+}
+
+// Health is health of DB handle.
+type Health struct {
+	Stats    sql.DBStats
+	Latency  time.Duration // time to run the probe query
+	Version  string
+	ReadOnly bool
+	Role     string // RolePrimary or RoleReplica, or empty for SQLite and MySQL without privileges to check it
+}
+
+// NewHealthChecker returns HealthChecker of DB handle.
+func NewHealthChecker(db *sqlx.DB) *HealthChecker {
+	return &HealthChecker{DB: db}
+}
+
+// Liveness pings DB.
+func (h *HealthChecker) Liveness(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout())
+	defer cancel()
+
+	return h.DB.PingContext(ctx)
+}
+
+// Readiness pings DB and runs the probe query.
+func (h *HealthChecker) Readiness(ctx context.Context) error {
+	_, err := h.probe(ctx)
+	return err
+}
+
+// Check returns health of DB handle with pool statistics and server facts.
+func (h *HealthChecker) Check(ctx context.Context) (*Health, error) {
+	latency, err := h.probe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout())
+	defer cancel()
+
+	health := &Health{Stats: h.DB.Stats(), Latency: latency}
+	query, ok := serverFactsQueries[h.DB.DriverName()]
+	if !ok {
+		return health, nil
+	}
+
+	var replica bool
+	if err := h.DB.QueryRowContext(ctx, query).Scan(&health.Version, &health.ReadOnly, &replica); err != nil {
+		return nil, fmt.Errorf("failed to get server facts: %w", err)
+	}
+
+	switch h.DB.DriverName() {
+	case sqliteDriver:
+		return health, nil
+	case mysqlDriver:
+		var ok bool
+		if replica, ok = h.mysqlReplica(ctx); !ok {
+			return health, nil
+		}
+	}
+
+	health.Role = RolePrimary
+	if replica {
+		health.Role = RoleReplica
+	}
+	return health, nil
+}
+
+// mysqlReplica reports whether MySQL has replication channels, and whether it could be checked.
+func (h *HealthChecker) mysqlReplica(ctx context.Context) (replica, ok bool) {
+	for _, query := range mysqlReplicaQueries {
+		rows, err := h.DB.QueryContext(ctx, query)
+		if err != nil {
+			// Lack of privileges or an older server
+			continue
+		}
+		replica = rows.Next()
+		if err := multierr.Append(rows.Err(), rows.Close()); err != nil {
+			continue
+		}
+		return replica, true
+	}
+	return false, false
+}
+
+// probe pings DB and runs the probe query, and returns the time to run it.
+func (h *HealthChecker) probe(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout())
+	defer cancel()
+
+	if err := h.DB.PingContext(ctx); err != nil {
+		return 0, err
+	}
+
+	query := h.ProbeQuery
+	if query == "" {
+		query = defaultProbeQuery
+	}
+
+	start := time.Now()
+	rows, err := h.DB.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run probe query: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// timeout returns the timeout of each check.
+func (h *HealthChecker) timeout() time.Duration {
+	if h.Timeout <= 0 {
+		return defaultProbeTimeout
+	}
+	return h.Timeout
+}
+
+// String returns health in lines of "key: value".
+func (h *Health) String() string {
+	role := h.Role
+	if role == "" {
+		role = "-"
+	}
+
+	lines := []string{
+		fmt.Sprintf("version: %s", h.Version),
+		fmt.Sprintf("role: %s", role),
+		fmt.Sprintf("read_only: %t", h.ReadOnly),
+		fmt.Sprintf("latency: %s", h.Latency),
+		fmt.Sprintf("max_open_connections: %d", h.Stats.MaxOpenConnections),
+		fmt.Sprintf("open_connections: %d", h.Stats.OpenConnections),
+		fmt.Sprintf("in_use: %d", h.Stats.InUse),
+		fmt.Sprintf("idle: %d", h.Stats.Idle),
+		fmt.Sprintf("wait_count: %d", h.Stats.WaitCount),
+		fmt.Sprintf("wait_duration: %s", h.Stats.WaitDuration),
+	}
+	return strings.Join(lines, "\n")
+}
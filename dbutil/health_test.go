@@ -0,0 +1,93 @@
+package dbutil_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+func TestHealthChecker(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	cfg, err := dbutil.ParseDSN("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := dbutil.OpenContext(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	h := dbutil.NewHealthChecker(db)
+	if err := h.Liveness(ctx); err != nil {
+		t.Error(err)
+	}
+	if err := h.Readiness(ctx); err != nil {
+		t.Error(err)
+	}
+
+	health, err := h.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Version == "" || health.Role != "" || health.ReadOnly {
+		t.Errorf("server facts want: version of SQLite without role, got: %+v", health)
+	}
+	if health.Stats.MaxOpenConnections != 1 || health.Stats.OpenConnections != 1 {
+		t.Errorf("stats want: 1 connection, got: %+v", health.Stats)
+	}
+	if got := health.String(); !strings.Contains(got, "version: "+health.Version) {
+		t.Errorf("want: lines of health, got: %s", got)
+	}
+
+	h.ProbeQuery = "SELECT * FROM " + dummy
+	if err := h.Readiness(ctx); err == nil {
+		t.Error("want: error of probe query, got: nil")
+	}
+	if _, err := h.Check(ctx); err == nil {
+		t.Error("want: error of probe query, got: nil")
+	}
+}
+
+func TestHealthCheckerRole(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		path string
+	}{
+		"mysql": {mysqlCfgPath},
+		"pgsql": {pgsqlCfgPath},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+			defer cancel()
+
+			db, err := dbutil.NewDBContext(ctx, dbutil.NewConfigFile(cfgType, tt.path, cfgSection))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			// DB of tests does not replicate.
+			health, err := dbutil.NewHealthChecker(db).Check(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if health.Role != dbutil.RolePrimary || health.ReadOnly {
+				t.Errorf("want: the writable primary, got: %+v", health)
+			}
+		})
+	}
+}
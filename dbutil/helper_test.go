@@ -19,14 +19,18 @@ const (
 	non     = 0
 
 	// Config Common
-	cfgType     = "ini"
-	cfgSection  = "test_dbutil_section"
-	cfgDatabase = "test_dbutil_db"
-	cfgUsername = "exampleuser"
-	cfgPassword = "examplepasswd"
-	cfgProtocol = "tcp"
-	cfgTz       = "Asia/Tokyo"
-	cfgSSLMode  = "disable" // for PostgreSQL
+	cfgType        = "ini"
+	cfgSection     = "test_dbutil_section"
+	urlSection     = "test_dbutil_pgsql_section" // for PostgreSQL in url.dsn
+	sessionSection = "test_session_section"      // for SQLite in sqlite.dsn
+	driverSection  = "test_driver_section"       // for MySQL and PostgreSQL
+	hostsSection   = "test_hosts_section"        // for PostgreSQL in pgsql.dsn
+	cfgDatabase    = "test_dbutil_db"
+	cfgUsername    = "exampleuser"
+	cfgPassword    = "examplepasswd"
+	cfgProtocol    = "tcp"
+	cfgTz          = "Asia/Tokyo"
+	cfgSSLMode     = "disable" // for PostgreSQL
 
 	// Config MySQL
 	mysqlHost   = "go_db_mysql"
@@ -40,6 +44,11 @@ const (
 	pgsqlDriver = "pgx"
 	pgsqlPort   = 5432
 
+	// Config SQLite
+	sqliteDBType   = "sqlite"
+	sqliteDriver   = "sqlite3"
+	sqliteDatabase = "/tmp/test_dbutil_db.sqlite3"
+
 	// Dummy
 	dummy     = "dummy"
 	dummyPort = 9999
@@ -48,20 +57,29 @@ const (
 	queryInsert = `INSERT INTO users (name, email, status, created_at, updated_at) 
 VALUES (:name, :email, :status, :created_at, :updated_at);`
 	querySelect = `SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status;`
-	queryUpdate = `UPDATE users SET status = :afterSts, updated_at = NOW() WHERE id = :id AND status = :beforeSts;`
+	queryUpdate = `UPDATE users SET status = :afterSts, updated_at = CURRENT_TIMESTAMP WHERE id = :id AND status = :beforeSts;`
+	// Queries of structs
+	queryInsertOpt     = `INSERT INTO users (name, email) VALUES (:name, :email);`
+	queryUpdateRow     = `UPDATE users SET name = :name, updated_at = :updated_at WHERE id = :id;`
+	queryUpdateVersion = `UPDATE users SET name = :name, updated_at = :updated_at, version = version + 1 WHERE id = :id AND version = :version;`
 )
 
 var (
 	// Path
-	testDir       = string(filepath.Separator) + filepath.Join("go", "src", "work", "testdata")
-	mysqlCfgPath  = filepath.Join(testDir, "mysql.dsn")
-	pgsqlCfgPath  = filepath.Join(testDir, "pgsql.dsn")
-	beforeSQLPath = filepath.Join(testDir, "before_update.sql")
+	testDir        = string(filepath.Separator) + filepath.Join("go", "src", "work", "testdata")
+	mysqlCfgPath   = filepath.Join(testDir, "mysql.dsn")
+	pgsqlCfgPath   = filepath.Join(testDir, "pgsql.dsn")
+	sqliteCfgPath  = filepath.Join(testDir, "sqlite.dsn")
+	urlCfgPath     = filepath.Join(testDir, "url.dsn")
+	invalidCfgPath = filepath.Join(testDir, "invalid.dsn")
+	beforeSQLPath  = filepath.Join(testDir, "before_update.sql")
+	sqliteDDLPath  = filepath.Join(testDir, "..", "examples", "example", "schema", "sqlite.sql")
 
 	// Query
 	queryTruncateTbls = map[string]string{
-		mysqlDriver: `TRUNCATE TABLE users;`,
-		pgsqlDriver: `TRUNCATE TABLE users RESTART IDENTITY;`,
+		mysqlDriver:  `TRUNCATE TABLE users;`,
+		pgsqlDriver:  `TRUNCATE TABLE users RESTART IDENTITY;`,
+		sqliteDriver: `DELETE FROM users;`,
 	}
 )
 
@@ -85,8 +103,12 @@ func prepareDB(t *testing.T, dbType, sqlPath string) {
 		t.Fatal(err)
 	}
 
-	queryTruncateTbl := queryTruncateTbls[db.DriverName()]
 	args := make(map[string]any)
+	if db.DriverName() == sqliteDriver {
+		createTable(ctx, t, db)
+	}
+
+	queryTruncateTbl := queryTruncateTbls[db.DriverName()]
 	_, err = sqlx.NamedExecContext(ctx, db, queryTruncateTbl, args)
 	if err != nil {
 		t.Fatal(err)
@@ -98,6 +120,20 @@ func prepareDB(t *testing.T, dbType, sqlPath string) {
 	}
 }
 
+// createTable creates users table because SQLite has no setup container.
+func createTable(ctx context.Context, t *testing.T, db *sqlx.DB) {
+	t.Helper()
+
+	query, err := os.ReadFile(sqliteDDLPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.ExecContext(ctx, string(query)); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // expectedConfig returns the Config that tests expect.
 func expectedConfig(t *testing.T, dbType string) *dbutil.Config {
 	t.Helper()
@@ -129,6 +165,15 @@ func expectedConfig(t *testing.T, dbType string) *dbutil.Config {
 		cfg.SSLMode = cfgSSLMode
 		cfg.DataSrc = dbutil.ExportDataSrcPgSQL(cfg)
 		return cfg
+	case sqliteDBType:
+		cfg = &dbutil.Config{
+			Type:     dbType,
+			Database: sqliteDatabase,
+			Tz:       cfgTz,
+			Driver:   sqliteDriver,
+		}
+		cfg.DataSrc = dbutil.ExportDataSrcSQLite(cfg)
+		return cfg
 	default:
 		return nil
 	}
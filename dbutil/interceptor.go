@@ -0,0 +1,287 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Op is a kind of operation which interceptors observe.
+type Op string
+
+// Operations of Event
+const (
+	OpQuery    Op = "query"
+	OpExec     Op = "exec"
+	OpBegin    Op = "begin"
+	OpCommit   Op = "commit"
+	OpRollback Op = "rollback"
+)
+
+// Event is an operation which interceptors observe.
+type Event struct {
+	Op Op
+	// Query and Args may be changed by Before. e.g. to tag the query
+	// They are empty for transaction lifecycle events.
+	Query string
+	Args  []any
+	// The following are set after the operation.
+	// Queries return before their rows are read, so Duration is the time to the first row,
+	// and Err does not have errors of reading and scanning rows.
+	Start        time.Time
+	Duration     time.Duration
+	RowsAffected int64 // -1 if it is unknown
+	Err          error
+	// DB is DB handle which runs the operation. Use it to run other queries such as EXPLAIN.
+	DB *sqlx.DB
+}
+
+// Interceptor observes or changes operations run through DB and Tx.
+type Interceptor interface {
+	// Before is called before the operation, and returns the context passed to the operation and After.
+	// An error rejects the operation. (e.g. policy checks)
+	Before(ctx context.Context, ev *Event) (context.Context, error)
+	// After is called after the operation with its result.
+	// It is called when queries return, before their rows are read.
+	After(ctx context.Context, ev *Event)
+}
+
+// InterceptorFuncs is Interceptor made of functions. Nil functions are skipped.
+type InterceptorFuncs struct {
+	BeforeFunc func(ctx context.Context, ev *Event) (context.Context, error)
+	AfterFunc  func(ctx context.Context, ev *Event)
+}
+
+// Before calls BeforeFunc.
+func (f InterceptorFuncs) Before(ctx context.Context, ev *Event) (context.Context, error) {
+	if f.BeforeFunc == nil {
+		return ctx, nil
+	}
+	return f.BeforeFunc(ctx, ev)
+}
+
+// After calls AfterFunc.
+func (f InterceptorFuncs) After(ctx context.Context, ev *Event) {
+	if f.AfterFunc != nil {
+		f.AfterFunc(ctx, ev)
+	}
+}
+
+// chain is interceptors which are called in order before operations and in reverse order after them.
+type chain []Interceptor
+
+// run runs the operation between Before and After of interceptors.
+// If Before of an interceptor fails, After is called only for the interceptors before it.
+func run[R any](ctx context.Context, c chain, ev *Event, fn func(ctx context.Context, query string, args ...any) (R, error)) (R, error) {
+	var result R
+	ev.RowsAffected = -1
+
+	ctxs := make([]context.Context, 0, len(c))
+	var err error
+	for _, ic := range c {
+		next, berr := ic.Before(ctx, ev)
+		if berr != nil {
+			err = berr
+			break
+		}
+		ctx = next
+		ctxs = append(ctxs, ctx)
+	}
+
+	ev.Start = time.Now()
+	if err == nil {
+		result, err = fn(ctx, ev.Query, ev.Args...)
+	}
+	ev.Duration = time.Since(ev.Start)
+	ev.Err = err
+
+	for i := len(ctxs) - 1; i >= 0; i-- {
+		c[i].After(ctxs[i], ev)
+	}
+
+	return result, err
+}
+
+// query runs a query through interceptors.
+func (c chain) query(ctx context.Context, db *sqlx.DB, query string, args []any,
+	fn func(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)) (*sqlx.Rows, error) {
+	return run(ctx, c, &Event{Op: OpQuery, Query: query, Args: args, DB: db}, fn)
+}
+
+// exec runs a query which returns no rows through interceptors.
+func (c chain) exec(ctx context.Context, db *sqlx.DB, query string, args []any,
+	fn func(ctx context.Context, query string, args ...any) (sql.Result, error)) (sql.Result, error) {
+	ev := &Event{Op: OpExec, Query: query, Args: args, DB: db}
+	return run(ctx, c, ev, func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+		result, err := fn(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		if num, err := result.RowsAffected(); err == nil {
+			ev.RowsAffected = num
+		}
+		return result, nil
+	})
+}
+
+// lifecycle runs a transaction lifecycle event through interceptors.
+func (c chain) lifecycle(ctx context.Context, db *sqlx.DB, op Op, fn func(ctx context.Context) error) error {
+	_, err := run(ctx, c, &Event{Op: op, DB: db}, func(ctx context.Context, _ string, _ ...any) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+var (
+	_ sqlx.ExtContext = (*DB)(nil)
+	_ Transaction     = (*Tx)(nil)
+)
+
+// DB is DB handle which runs queries of the helpers through interceptors.
+// Methods of the embedded *sqlx.DB such as NamedExecContext are not intercepted,
+// so use the helpers or functions of sqlx package such as sqlx.NamedExecContext.
+type DB struct {
+	*sqlx.DB
+	chain chain
+}
+
+// NewDB returns DB handle which runs queries through interceptors.
+// Before of interceptors is called in order and After is called in reverse order.
+func NewDB(db *sqlx.DB, interceptors ...Interceptor) *DB {
+	return &DB{DB: db, chain: interceptors}
+}
+
+// QueryContext runs a query through interceptors.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows.Rows, nil
+}
+
+// QueryxContext runs a query through interceptors.
+func (db *DB) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	return db.chain.query(ctx, db.DB, query, args, db.DB.QueryxContext)
+}
+
+// QueryRowxContext runs a query which returns a row through interceptors.
+func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	var row *sqlx.Row
+	_, err := db.chain.query(ctx, db.DB, query, args, func(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+		row = db.DB.QueryRowxContext(ctx, query, args...)
+		return nil, row.Err()
+	})
+	if row == nil {
+		return rejectedRow(err)
+	}
+	return row
+}
+
+// rejectedRow returns a row which fails to scan by the error of an interceptor which has rejected the query.
+// sqlx.Row can be made only by a query, so it runs on DB handle which fails to connect by the error.
+func rejectedRow(err error) *sqlx.Row {
+	db := sqlx.NewDb(sql.OpenDB(rejectedConnector{err}), "")
+	defer db.Close()
+	return db.QueryRowx("")
+}
+
+// rejectedConnector is a connector and a driver which fail by the error.
+type rejectedConnector struct {
+	err error
+}
+
+func (c rejectedConnector) Connect(context.Context) (driver.Conn, error) { return nil, c.err }
+func (c rejectedConnector) Driver() driver.Driver                        { return c }
+func (c rejectedConnector) Open(string) (driver.Conn, error)             { return nil, c.err }
+
+// ExecContext runs a query which returns no rows through interceptors.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.chain.exec(ctx, db.DB, query, args, db.DB.ExecContext)
+}
+
+// BeginTxx starts a transaction which runs queries and lifecycle events through interceptors.
+// Commit and Rollback run on ctx, not on the context which Before has returned for the begin event.
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	var tx *sqlx.Tx
+	err := db.chain.lifecycle(ctx, db.DB, OpBegin, func(ctx context.Context) error {
+		var err error
+		tx, err = db.DB.BeginTxx(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db.DB, ctx: ctx, chain: db.chain}, nil
+}
+
+// Tx is a transaction which runs queries of the helpers and lifecycle events through interceptors.
+type Tx struct {
+	*sqlx.Tx
+	db    *sqlx.DB
+	ctx   context.Context // context of BeginTxx for Commit and Rollback
+	chain chain
+}
+
+// QueryContext runs a query on the transaction through interceptors.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := tx.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows.Rows, nil
+}
+
+// QueryxContext runs a query on the transaction through interceptors.
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	return tx.chain.query(ctx, tx.db, query, args, tx.Tx.QueryxContext)
+}
+
+// QueryRowxContext runs a query which returns a row on the transaction through interceptors.
+func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	var row *sqlx.Row
+	_, err := tx.chain.query(ctx, tx.db, query, args, func(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+		row = tx.Tx.QueryRowxContext(ctx, query, args...)
+		return nil, row.Err()
+	})
+	if row == nil {
+		return rejectedRow(err)
+	}
+	return row
+}
+
+// ExecContext runs a query which returns no rows on the transaction through interceptors.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.chain.exec(ctx, tx.db, query, args, tx.Tx.ExecContext)
+}
+
+// Commit commits the transaction through interceptors on the context of BeginTxx.
+// Use CommitContext to pass interceptors another context such as a span of the caller.
+func (tx *Tx) Commit() error {
+	return tx.CommitContext(tx.ctx)
+}
+
+// CommitContext commits the transaction through interceptors on the context.
+// database/sql can not cancel committing, so ctx is used only by interceptors.
+func (tx *Tx) CommitContext(ctx context.Context) error {
+	return tx.chain.lifecycle(ctx, tx.db, OpCommit, func(context.Context) error {
+		return tx.Tx.Commit()
+	})
+}
+
+// Rollback aborts the transaction through interceptors on the context of BeginTxx.
+// Use RollbackContext to pass interceptors another context such as a span of the caller.
+func (tx *Tx) Rollback() error {
+	return tx.RollbackContext(tx.ctx)
+}
+
+// RollbackContext aborts the transaction through interceptors on the context.
+// database/sql can not cancel rolling back, so ctx is used only by interceptors.
+func (tx *Tx) RollbackContext(ctx context.Context) error {
+	return tx.chain.lifecycle(ctx, tx.db, OpRollback, func(context.Context) error {
+		return tx.Tx.Rollback()
+	})
+}
@@ -0,0 +1,245 @@
+package dbutil_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+// recorder records events of interceptors.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// interceptor returns Interceptor which records its name with the operation.
+func (r *recorder) interceptor(name string, beforeErr error) dbutil.Interceptor {
+	return dbutil.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, ev *dbutil.Event) (context.Context, error) {
+			r.add("before " + name + " " + string(ev.Op))
+			return ctx, beforeErr
+		},
+		AfterFunc: func(_ context.Context, ev *dbutil.Event) {
+			r.add("after " + name + " " + string(ev.Op))
+		},
+	}
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestInterceptor(t *testing.T) {
+	t.Parallel()
+
+	errRejected := errors.New("rejected")
+
+	cases := map[string]struct {
+		interceptors func(r *recorder) []dbutil.Interceptor
+		run          func(ctx context.Context, db *dbutil.DB) error
+		want         []string
+		wantErr      error
+	}{
+		"select": {
+			func(r *recorder) []dbutil.Interceptor {
+				return []dbutil.Interceptor{r.interceptor("a", nil), r.interceptor("b", nil)}
+			},
+			func(ctx context.Context, db *dbutil.DB) error {
+				_, err := dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+				return err
+			},
+			[]string{"before a query", "before b query", "after b query", "after a query"},
+			nil,
+		},
+		"rejected": {
+			func(r *recorder) []dbutil.Interceptor {
+				return []dbutil.Interceptor{r.interceptor("a", nil), r.interceptor("b", errRejected), r.interceptor("c", nil)}
+			},
+			func(ctx context.Context, db *dbutil.DB) error {
+				_, err := dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+				return err
+			},
+			[]string{"before a query", "before b query", "after a query"},
+			errRejected,
+		},
+		"rejected row": {
+			func(r *recorder) []dbutil.Interceptor {
+				return []dbutil.Interceptor{r.interceptor("a", errRejected)}
+			},
+			func(ctx context.Context, db *dbutil.DB) error {
+				_, err := dbutil.GetContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+				return err
+			},
+			[]string{"before a query"},
+			errRejected,
+		},
+		"commit": {
+			func(r *recorder) []dbutil.Interceptor {
+				return []dbutil.Interceptor{r.interceptor("a", nil)}
+			},
+			func(ctx context.Context, db *dbutil.DB) error {
+				tx, err := db.BeginTxx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				if _, err := dbutil.UpdateTxContext(ctx, tx, queryUpdate, map[string]any{"id": 1, "beforeSts": active, "afterSts": non}); err != nil {
+					return err
+				}
+				return tx.Commit()
+			},
+			[]string{"before a begin", "after a begin", "before a exec", "after a exec", "before a commit", "after a commit"},
+			nil,
+		},
+		"rollback": {
+			func(r *recorder) []dbutil.Interceptor {
+				return []dbutil.Interceptor{r.interceptor("a", nil)}
+			},
+			func(ctx context.Context, db *dbutil.DB) error {
+				tx, err := db.BeginTxx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				_, err = dbutil.UpdateTxContext(ctx, tx, "UPDATE dummy SET status = :afterSts WHERE id = :id", map[string]any{"id": 1, "afterSts": non})
+				return err
+			},
+			[]string{"before a begin", "after a begin", "before a exec", "after a exec", "before a rollback", "after a rollback"},
+			nil,
+		},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+			defer cancel()
+
+			db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "interceptor"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			r := &recorder{}
+			err = tt.run(ctx, dbutil.NewDB(db, tt.interceptors(r)...))
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("want: %v, got: %v", tt.wantErr, err)
+			}
+
+			if got := strings.Join(r.events, ", "); got != strings.Join(tt.want, ", ") {
+				t.Errorf("want: %v, got: %v", tt.want, r.events)
+			}
+		})
+	}
+}
+
+func TestInterceptorEvent(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var got *dbutil.Event
+	tag := dbutil.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, ev *dbutil.Event) (context.Context, error) {
+			ev.Query = "/* tagged */ " + ev.Query
+			return ctx, nil
+		},
+		AfterFunc: func(_ context.Context, ev *dbutil.Event) {
+			got = ev
+		},
+	}
+
+	tx, err := dbutil.NewDB(db, tag).BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	num, err := dbutil.UpdateTxContext(ctx, tx, queryUpdate, map[string]any{"id": 1, "beforeSts": active, "afterSts": non})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Op != dbutil.OpExec {
+		t.Errorf("op want: %s, got: %s", dbutil.OpExec, got.Op)
+	}
+	if !strings.HasPrefix(got.Query, "/* tagged */") {
+		t.Errorf("query want: tagged, got: %s", got.Query)
+	}
+	if got.RowsAffected != num || num != 1 {
+		t.Errorf("rows affected want: 1, got: %d", got.RowsAffected)
+	}
+	if got.Err != nil || got.DB != db {
+		t.Errorf("want: no error and DB handle, got: %v, %v", got.Err, got.DB)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Op != dbutil.OpCommit || got.RowsAffected != -1 {
+		t.Errorf("want: commit without rows affected, got: %s, %d", got.Op, got.RowsAffected)
+	}
+}
+
+// ctxKey is a context key of tests.
+type ctxKey string
+
+func TestInterceptorTxContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "tx_context"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Before of the begin event returns a context such as its span, and the others record their context.
+	got := make(map[dbutil.Op]string)
+	ic := dbutil.InterceptorFuncs{
+		BeforeFunc: func(ctx context.Context, ev *dbutil.Event) (context.Context, error) {
+			if ev.Op == dbutil.OpBegin {
+				return context.WithValue(ctx, ctxKey("caller"), "begin"), nil
+			}
+			got[ev.Op], _ = ctx.Value(ctxKey("caller")).(string)
+			return ctx, nil
+		},
+	}
+	idb := dbutil.NewDB(db, ic)
+
+	tx, err := idb.BeginTxx(context.WithValue(ctx, ctxKey("caller"), "tx"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err = idb.BeginTxx(context.WithValue(ctx, ctxKey("caller"), "tx"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RollbackContext(context.WithValue(ctx, ctxKey("caller"), "rollback")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Commit runs on the context of BeginTxx, not on the one of Before.
+	if got[dbutil.OpCommit] != "tx" || got[dbutil.OpRollback] != "rollback" {
+		t.Errorf("want: commit on tx and rollback on rollback, got: %v", got)
+	}
+}
@@ -0,0 +1,164 @@
+package dbutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const defaultExplainTimeout = 10 * time.Second
+
+// QueryLogger logs queries through zap.
+// Close it to stop EXPLAIN running in background.
+type QueryLogger struct {
+	Logger *zap.Logger
+	// Queries slower than SlowThreshold are logged at warn level. 0 means no threshold.
+	SlowThreshold time.Duration
+	// Explain adds the result of EXPLAIN to logs of slow queries.
+	// EXPLAIN runs on another connection after the query, so the logs are written asynchronously.
+	Explain bool
+	// Redact returns an argument to log. RedactArg is used if it is nil.
+	Redact func(arg any) any
+
+	mu     sync.Mutex // guards closed, ctx and cancel
+	closed bool
+	ctx    context.Context // parent of EXPLAIN, canceled by Close
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// RedactArg masks strings and bytes which may have personal information.
+// The other types such as numbers, booleans and times are logged as they are.
+func RedactArg(arg any) any {
+	switch arg.(type) {
+	case string, []byte:
+		return redactedPassword
+	default:
+		return arg
+	}
+}
+
+var _ Interceptor = (*QueryLogger)(nil)
+
+// Before does nothing because queries are logged after they run.
+func (l *QueryLogger) Before(ctx context.Context, _ *Event) (context.Context, error) {
+	return ctx, nil
+}
+
+// After logs the operation.
+func (l *QueryLogger) After(_ context.Context, ev *Event) {
+	if l.Logger == nil {
+		return
+	}
+
+	if ev.Op != OpQuery && ev.Op != OpExec {
+		if ev.Err != nil {
+			l.Logger.Error("transaction failed", zap.String("op", string(ev.Op)), zap.Error(ev.Err))
+			return
+		}
+		l.Logger.Debug("transaction", zap.String("op", string(ev.Op)), zap.Duration("duration", ev.Duration))
+		return
+	}
+
+	redact := l.Redact
+	if redact == nil {
+		redact = RedactArg
+	}
+	redacted := make([]any, len(ev.Args))
+	for i, arg := range ev.Args {
+		redacted[i] = redact(arg)
+	}
+
+	fields := []zap.Field{
+		zap.String("query", ev.Query),
+		zap.Any("args", redacted),
+		zap.Duration("duration", ev.Duration),
+	}
+	if ev.RowsAffected >= 0 {
+		fields = append(fields, zap.Int64("rows_affected", ev.RowsAffected))
+	}
+
+	slow := l.SlowThreshold > 0 && ev.Duration >= l.SlowThreshold
+	switch {
+	case ev.Err != nil:
+		l.Logger.Error("query failed", append(fields, zap.Error(ev.Err))...)
+	case slow && l.Explain && l.startExplain():
+		// The query may still hold its connection by rows or a transaction.
+		db, query, args := ev.DB, ev.Query, ev.Args
+		go func() {
+			defer l.wg.Done()
+			ctx, cancel := context.WithTimeout(l.ctx, defaultExplainTimeout)
+			defer cancel()
+			l.Logger.Warn("slow query", append(fields, explain(ctx, db, query, args))...)
+		}()
+	case slow:
+		l.Logger.Warn("slow query", fields...)
+	default:
+		l.Logger.Debug("query", fields...)
+	}
+}
+
+// startExplain counts EXPLAIN to run in background, and reports false after QueryLogger is closed.
+func (l *QueryLogger) startExplain() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return false
+	}
+	if l.ctx == nil {
+		l.ctx, l.cancel = context.WithCancel(context.Background())
+	}
+	l.wg.Add(1)
+	return true
+}
+
+// Close cancels EXPLAIN running in background and waits for its logs.
+// Slow queries are logged without EXPLAIN after it is closed.
+func (l *QueryLogger) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	return nil
+}
+
+// explain returns a log field of the result of EXPLAIN.
+func explain(ctx context.Context, db *sqlx.DB, query string, args []any) zap.Field {
+	prefix := "EXPLAIN "
+	if db.DriverName() == sqliteDriver {
+		prefix = "EXPLAIN QUERY PLAN "
+	}
+
+	rows, err := db.QueryxContext(ctx, prefix+query, args...)
+	if err != nil {
+		return zap.NamedError("explain_error", err)
+	}
+	defer rows.Close()
+
+	var plan []map[string]any
+	for rows.Next() {
+		row := make(map[string]any)
+		if err := rows.MapScan(row); err != nil {
+			return zap.NamedError("explain_error", err)
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		plan = append(plan, row)
+	}
+	if err := rows.Err(); err != nil {
+		return zap.NamedError("explain_error", err)
+	}
+
+	return zap.Any("explain", plan)
+}
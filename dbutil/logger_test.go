@@ -0,0 +1,185 @@
+package dbutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestQueryLogger(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		logger  func(*zap.Logger) *dbutil.QueryLogger
+		run     func(ctx context.Context, db *dbutil.DB) error
+		level   zapcore.Level
+		field   string
+		wantErr bool
+	}{
+		"select": {
+			func(l *zap.Logger) *dbutil.QueryLogger { return &dbutil.QueryLogger{Logger: l} },
+			func(ctx context.Context, db *dbutil.DB) error {
+				_, err := dbutil.SelectContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+				return err
+			},
+			zapcore.DebugLevel, "args", false,
+		},
+		"update": {
+			func(l *zap.Logger) *dbutil.QueryLogger { return &dbutil.QueryLogger{Logger: l} },
+			func(ctx context.Context, db *dbutil.DB) error {
+				tx, err := db.BeginTxx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				if _, err := dbutil.UpdateTxContext(ctx, tx, queryUpdate, map[string]any{"id": 1, "beforeSts": active, "afterSts": non}); err != nil {
+					return err
+				}
+				return tx.Commit()
+			},
+			zapcore.DebugLevel, "rows_affected", false,
+		},
+		"error": {
+			func(l *zap.Logger) *dbutil.QueryLogger { return &dbutil.QueryLogger{Logger: l} },
+			func(ctx context.Context, db *dbutil.DB) error {
+				_, err := dbutil.SelectContext[User](ctx, db, "SELECT * FROM dummy WHERE id = :id", map[string]any{"id": 1})
+				return err
+			},
+			zapcore.ErrorLevel, "error", true,
+		},
+		"slow": {
+			func(l *zap.Logger) *dbutil.QueryLogger {
+				return &dbutil.QueryLogger{Logger: l, SlowThreshold: time.Nanosecond}
+			},
+			func(ctx context.Context, db *dbutil.DB) error {
+				_, err := dbutil.GetContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+				return err
+			},
+			zapcore.WarnLevel, "duration", false,
+		},
+		"explain": {
+			func(l *zap.Logger) *dbutil.QueryLogger {
+				return &dbutil.QueryLogger{Logger: l, SlowThreshold: time.Nanosecond, Explain: true}
+			},
+			func(ctx context.Context, db *dbutil.DB) error {
+				_, err := dbutil.GetContext[User](ctx, db, querySelect, map[string]any{"id": 1, "status": active})
+				return err
+			},
+			zapcore.WarnLevel, "explain", false,
+		},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+			defer cancel()
+
+			db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "logger"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			core, logs := observer.New(zapcore.DebugLevel)
+			err = tt.run(ctx, dbutil.NewDB(db, tt.logger(zap.New(core))))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error want: %t, got: %v", tt.wantErr, err)
+			}
+
+			// EXPLAIN is logged asynchronously.
+			for logs.FilterLevelExact(tt.level).FilterFieldKey("query").Len() == 0 && ctx.Err() == nil {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// Transaction lifecycle events are logged without queries.
+			entries := logs.FilterLevelExact(tt.level).FilterFieldKey("query").All()
+			if len(entries) == 0 {
+				t.Fatalf("want: %s log, got: %v", tt.level, logs.All())
+			}
+			fields := entries[0].ContextMap()
+			if _, ok := fields[tt.field]; !ok {
+				t.Errorf("want: %s field, got: %v", tt.field, fields)
+			}
+			if _, ok := fields["explain_error"]; ok {
+				t.Errorf("want: no explain_error, got: %v", fields)
+			}
+		})
+	}
+}
+
+func TestQueryLoggerClose(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "logger_close"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := &dbutil.QueryLogger{Logger: zap.New(core), SlowThreshold: time.Nanosecond, Explain: true}
+	get := func() {
+		if _, err := dbutil.GetContext[User](ctx, dbutil.NewDB(db, l), querySelect, map[string]any{"id": 1, "status": active}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close waits for EXPLAIN in background, which may be canceled.
+	get()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := logs.FilterMessage("slow query").Len(); n != 1 {
+		t.Fatalf("want: 1 slow query log after Close, got: %d", n)
+	}
+
+	// Slow queries are logged without EXPLAIN after Close.
+	get()
+	entries := logs.FilterMessage("slow query").All()
+	if len(entries) != 2 {
+		t.Fatalf("want: 2 slow query logs, got: %d", len(entries))
+	}
+	if _, ok := entries[1].ContextMap()["explain"]; ok {
+		t.Errorf("want: no explain after Close, got: %v", entries[1].ContextMap())
+	}
+	if err := l.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedactArg(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	cases := map[string]struct {
+		arg  any
+		want any
+	}{
+		"string": {cfgPassword, "xxxxx"},
+		"bytes":  {[]byte(cfgPassword), "xxxxx"},
+		"int":    {1, 1},
+		"time":   {now, now},
+		"nil":    {nil, nil},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := dbutil.RedactArg(tt.arg); got != tt.want {
+				t.Errorf("want: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package dbutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a key and its value of DB config file.
+type setting struct {
+	key string
+	val any // string, bool, number or map[string]string
+}
+
+// MarshalConfig returns a section of DB config file in the type. (ini, yaml, toml or json)
+// Keys which have zero values are omitted, and the password is encoded by the secret provider as ParseConfig expects.
+// The provider is base64 if it is empty.
+func MarshalConfig(typ, section string, cfg *Config, provider string) ([]byte, error) {
+	settings := cfg.settings()
+	for i, s := range settings {
+		if s.key != "password" {
+			continue
+		}
+		secret, err := encodeSecret(provider, cfg.Password)
+		if err != nil {
+			return nil, err
+		}
+		settings[i].val = secret
+	}
+
+	switch typ {
+	case "ini":
+		return marshalINI(section, settings)
+	case "yaml", "yml":
+		return yaml.Marshal(map[string]any{section: settingsMap(settings)})
+	case "toml":
+		return toml.Marshal(map[string]any{section: settingsMap(settings)})
+	case "json":
+		b, err := json.MarshalIndent(map[string]any{section: settingsMap(settings)}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported config type: %q", typ)
+	}
+}
+
+// settings returns keys and values of DB config in order of fields.
+func (cfg *Config) settings() []setting {
+	var settings []setting
+
+	val := reflect.ValueOf(*cfg)
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		key := typ.Field(i).Tag.Get("mapstructure")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(typ.Field(i).Name)
+		}
+
+		field := val.Field(i)
+		if field.IsZero() {
+			continue
+		}
+
+		switch v := field.Interface().(type) {
+		case time.Duration:
+			settings = append(settings, setting{key, v.String()})
+		case Statements:
+			settings = append(settings, setting{key, strings.Join(v, "; ")})
+		case []string:
+			settings = append(settings, setting{key, strings.Join(v, ",")})
+		case *bool:
+			settings = append(settings, setting{key, *v})
+		default:
+			settings = append(settings, setting{key, v})
+		}
+	}
+
+	return settings
+}
+
+// settingsMap returns settings as a map.
+func settingsMap(settings []setting) map[string]any {
+	m := make(map[string]any, len(settings))
+	for _, s := range settings {
+		m[s.key] = s.val
+	}
+	return m
+}
+
+// marshalINI returns a section of INI file.
+// Keys of maps are written with dots such as "session.sql_mode".
+func marshalINI(section string, settings []setting) ([]byte, error) {
+	f := ini.Empty()
+	sec, err := f.NewSection(section)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range settings {
+		m, ok := s.val.(map[string]string)
+		if !ok {
+			if _, err := sec.NewKey(s.key, fmt.Sprint(s.val)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, err := sec.NewKey(s.key+"."+name, m[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,64 @@
+package dbutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarshalConfig(t *testing.T) {
+	t.Parallel()
+
+	sections := map[string]struct {
+		path    string
+		section string
+	}{
+		"mysql":  {mysqlCfgPath, driverSection},
+		"pgsql":  {pgsqlCfgPath, hostsSection},
+		"sqlite": {sqliteCfgPath, sessionSection},
+	}
+
+	for name, sec := range sections {
+		for _, typ := range []string{"ini", "yaml", "toml", "json"} {
+			sec, typ := sec, typ
+			t.Run(name+"/"+typ, func(t *testing.T) {
+				t.Parallel()
+
+				want, err := dbutil.ParseConfig(cfgType, sec.path, sec.section)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				b, err := dbutil.MarshalConfig(typ, sec.section, want, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				path := filepath.Join(t.TempDir(), "marshal.dsn")
+				if err := os.WriteFile(path, b, 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := dbutil.ParseConfig(typ, path, sec.section)
+				if err != nil {
+					t.Fatalf("%v\n%s", err, b)
+				}
+
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("%s\n%s", diff, b)
+				}
+			})
+		}
+	}
+}
+
+func TestMarshalConfigErr(t *testing.T) {
+	t.Parallel()
+
+	if _, err := dbutil.MarshalConfig(dummy, cfgSection, expectedConfig(t, sqliteDBType), ""); err == nil {
+		t.Error("want: error, got: nil")
+	}
+}
@@ -0,0 +1,203 @@
+package dbutil
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Annotations of queries in .sql files
+var (
+	// e.g. -- name: SelectUser
+	queryNameRegexp = regexp.MustCompile(`^--\s*name:\s*(\S+)\s*$`)
+	// e.g. -- dialect: mysql, sqlite
+	queryDialectRegexp = regexp.MustCompile(`^--\s*dialect:\s*(.+?)\s*$`)
+)
+
+// Drivers by dialects of queries. DB types and driver names are accepted.
+var dialectDrivers = map[string]string{
+	mysqlDBType:  mysqlDriver,
+	pgsqlDBType:  pgsqlDriver,
+	pgsqlDriver:  pgsqlDriver,
+	"postgres":   pgsqlDriver,
+	sqliteDBType: sqliteDriver,
+	sqliteDriver: sqliteDriver,
+}
+
+// Queries are named queries loaded from .sql files.
+type Queries struct {
+	// queries by names and drivers. The empty driver is the query for any drivers.
+	queries map[string]map[string]Query
+}
+
+// LoadQueries loads queries from .sql files of the patterns in fsys, such as embed.FS or os.DirFS.
+// Each query starts with "-- name: <Name>", optionally followed by "-- dialect: <DB type or driver>, ...",
+// and continues until the next name. Comment lines right after the annotations are not part of the query.
+//
+//	-- name: TruncateUsers
+//	-- dialect: mysql
+//	TRUNCATE TABLE users;
+//
+//	-- name: TruncateUsers
+//	-- dialect: pgsql
+//	TRUNCATE TABLE users RESTART IDENTITY;
+func LoadQueries(fsys fs.FS, patterns ...string) (*Queries, error) {
+	q := &Queries{queries: make(map[string]map[string]Query)}
+	for _, pattern := range patterns {
+		paths, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no query files match %s", pattern)
+		}
+		for _, path := range paths {
+			if err := q.load(fsys, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return q, nil
+}
+
+// MustLoadQueries is like LoadQueries but panics if an error occurs.
+// It is for package level variables of embedded query files.
+func MustLoadQueries(fsys fs.FS, patterns ...string) *Queries {
+	q, err := LoadQueries(fsys, patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// queryEntry is a query which is being read.
+type queryEntry struct {
+	name     string
+	line     int // line number of the name
+	dialects []string
+	body     []string
+}
+
+// load loads queries of a file.
+func (q *Queries) load(fsys fs.FS, path string) error {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var entry *queryEntry
+	scanner := bufio.NewScanner(f)
+	for num := 1; scanner.Scan(); num++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if m := queryNameRegexp.FindStringSubmatch(trimmed); m != nil {
+			if err := q.add(path, entry); err != nil {
+				return err
+			}
+			entry = &queryEntry{name: m[1], line: num}
+			continue
+		}
+
+		if entry == nil {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return fmt.Errorf("%s:%d: query has no name annotation", path, num)
+			}
+			continue
+		}
+
+		if len(entry.body) == 0 {
+			if m := queryDialectRegexp.FindStringSubmatch(trimmed); m != nil {
+				for _, dialect := range strings.Split(m[1], ",") {
+					entry.dialects = append(entry.dialects, strings.TrimSpace(dialect))
+				}
+				continue
+			}
+			// Skip comments which describe the query.
+			if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+				continue
+			}
+		}
+		entry.body = append(entry.body, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return q.add(path, entry)
+}
+
+// add adds the query which has been read.
+func (q *Queries) add(path string, entry *queryEntry) error {
+	if entry == nil {
+		return nil
+	}
+
+	query := strings.TrimSpace(strings.Join(entry.body, "\n"))
+	if query == "" {
+		return fmt.Errorf("%s:%d: query %s is empty", path, entry.line, entry.name)
+	}
+
+	drivers := []string{""}
+	if len(entry.dialects) > 0 {
+		drivers = make([]string, 0, len(entry.dialects))
+		for _, dialect := range entry.dialects {
+			driver, ok := dialectDrivers[dialect]
+			if !ok {
+				return fmt.Errorf("%s:%d: unknown dialect %q of query %s", path, entry.line, dialect, entry.name)
+			}
+			drivers = append(drivers, driver)
+		}
+	}
+
+	if q.queries[entry.name] == nil {
+		q.queries[entry.name] = make(map[string]Query)
+	}
+	for _, driver := range drivers {
+		if _, ok := q.queries[entry.name][driver]; ok {
+			return fmt.Errorf("%s:%d: query %s is duplicated", path, entry.line, entry.name)
+		}
+		q.queries[entry.name][driver] = Query(query)
+	}
+
+	return nil
+}
+
+// Names returns names of the queries in order.
+func (q *Queries) Names() []string {
+	names := make([]string, 0, len(q.queries))
+	for name := range q.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Query returns the query of the name for the driver such as db.DriverName().
+// The query without dialects is returned if the driver has no variant.
+func (q *Queries) Query(name, driver string) (Query, error) {
+	variants, ok := q.queries[name]
+	if !ok {
+		return "", fmt.Errorf("query %s is not found", name)
+	}
+	if query, ok := variants[driver]; ok {
+		return query, nil
+	}
+	if query, ok := variants[""]; ok {
+		return query, nil
+	}
+	return "", fmt.Errorf("query %s is not found for %s", name, driver)
+}
+
+// MustQuery is like Query but panics if the query is not found.
+func (q *Queries) MustQuery(name, driver string) Query {
+	query, err := q.Query(name, driver)
+	if err != nil {
+		panic(err)
+	}
+	return query
+}
@@ -0,0 +1,126 @@
+package dbutil_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+func TestLoadQueries(t *testing.T) {
+	t.Parallel()
+
+	q, err := dbutil.LoadQueries(os.DirFS(testDir), "queries.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(q.Names(), ","); got != "SelectUser,TruncateUsers,UpdateStatus" {
+		t.Errorf("names want: SelectUser,TruncateUsers,UpdateStatus, got: %s", got)
+	}
+
+	cases := map[string]struct {
+		name    string
+		driver  string
+		want    string
+		wantErr bool
+	}{
+		"any":       {"SelectUser", mysqlDriver, "SELECT id, name, status, created_at, updated_at\nFROM users\nWHERE id = :id AND status = :status;", false},
+		"mysql":     {"TruncateUsers", mysqlDriver, queryTruncateTbls[mysqlDriver], false},
+		"pgsql":     {"TruncateUsers", pgsqlDriver, queryTruncateTbls[pgsqlDriver], false},
+		"sqlite":    {"TruncateUsers", sqliteDriver, queryTruncateTbls[sqliteDriver], false},
+		"no driver": {"TruncateUsers", "dummy", "", true},
+		"not found": {"DeleteUser", mysqlDriver, "", true},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := q.Query(tt.name, tt.driver)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error want: %t, got: %v", tt.wantErr, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("want: %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadQueriesErr(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		content string
+		want    string
+	}{
+		"no name":    {"SELECT 1;", "queries.sql:1: query has no name annotation"},
+		"empty":      {"-- name: Empty\n-- name: SelectOne\nSELECT 1;", "queries.sql:1: query Empty is empty"},
+		"dialect":    {"-- name: SelectOne\n-- dialect: oracle\nSELECT 1 FROM dual;", `queries.sql:1: unknown dialect "oracle" of query SelectOne`},
+		"duplicated": {"-- name: SelectOne\nSELECT 1;\n-- name: SelectOne\nSELECT 1;", "queries.sql:3: query SelectOne is duplicated"},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fsys := fstest.MapFS{"queries.sql": &fstest.MapFile{Data: []byte(tt.content)}}
+			_, err := dbutil.LoadQueries(fsys, "*.sql")
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("want: %s, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := dbutil.LoadQueries(fstest.MapFS{}, "*.sql"); err == nil {
+		t.Error("want: error of no files, got: nil")
+	}
+}
+
+func TestQueriesHelpers(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "queries"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	q := dbutil.MustLoadQueries(os.DirFS(testDir), "queries.sql")
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := map[string]any{"id": 1, "beforeSts": active, "afterSts": non}
+	num, err := dbutil.UpdateTxContext(ctx, tx, q.MustQuery("UpdateStatus", db.DriverName()), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if num != 1 {
+		t.Errorf("rows affected want: 1, got: %d", num)
+	}
+
+	// Queries can be kept in fields.
+	repo := struct{ selectUser dbutil.Query }{selectUser: q.MustQuery("SelectUser", db.DriverName())}
+	u, err := dbutil.GetContext[User](ctx, db, repo.selectUser, map[string]any{"id": 1, "status": non})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 {
+		t.Errorf("id want: 1, got: %d", u.ID)
+	}
+}
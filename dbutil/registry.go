@@ -0,0 +1,159 @@
+package dbutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+	"go.uber.org/multierr"
+)
+
+// ErrRegistryClosed is returned by Registry after it has been closed.
+var ErrRegistryClosed = errors.New("registry has been closed")
+
+// Registry has DB handles of sections in a DB config file such as primary, replica and analytics.
+// Each DB handle is opened on first use.
+type Registry struct {
+	mu      sync.Mutex
+	closed  bool
+	names   []string
+	entries map[string]*registryEntry
+}
+
+// registryEntry has DB config and DB handle of a section.
+type registryEntry struct {
+	mu  sync.Mutex
+	cfg *Config
+	db  *sqlx.DB
+}
+
+// NewRegistry returns Registry of sections in DB config file.
+// All sections are loaded if no sections are given.
+// Problems of all sections are returned at once.
+func NewRegistry(typ, path string, sections ...string) (*Registry, error) {
+	v, err := readConfigFile(typ, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sections) == 0 {
+		sections = configSections(v)
+	}
+
+	r := &Registry{entries: make(map[string]*registryEntry, len(sections))}
+	var errs error
+	for _, section := range sections {
+		cfg, err := parseConfigSection(v, path, section)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		r.names = append(r.names, section)
+		r.entries[section] = &registryEntry{cfg: cfg}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	return r, nil
+}
+
+// configSections returns names of sections in DB config file in order of names.
+func configSections(v *viper.Viper) []string {
+	var sections []string
+	for key, val := range v.AllSettings() {
+		if _, ok := val.(map[string]any); ok {
+			sections = append(sections, key)
+		}
+	}
+	sort.Strings(sections)
+	return sections
+}
+
+// Names returns names of sections in Registry.
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Config returns DB config of the section.
+func (r *Registry) Config(name string) (*Config, error) {
+	e, ok := r.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown section: %q", name)
+	}
+	return e.cfg, nil
+}
+
+// DBContext returns DB handle of the section, and opens it if it has not been opened yet.
+func (r *Registry) DBContext(ctx context.Context, name string) (*sqlx.DB, error) {
+	e, ok := r.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown section: %q", name)
+	}
+
+	// Lock the entry only so that the other sections can be opened at the same time.
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if r.isClosed() {
+		return nil, ErrRegistryClosed
+	}
+	if e.db != nil {
+		return e.db, nil
+	}
+
+	db, err := OpenContext(ctx, e.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open [%s]: %w", name, err)
+	}
+	e.db = db
+
+	return db, nil
+}
+
+// Health pings DB handles which have been opened, and returns the results by names of sections.
+// A nil error means the connection is healthy.
+func (r *Registry) Health(ctx context.Context) map[string]error {
+	health := make(map[string]error)
+	for _, name := range r.names {
+		e := r.entries[name]
+		e.mu.Lock()
+		db := e.db
+		e.mu.Unlock()
+
+		if db != nil {
+			health[name] = db.PingContext(ctx)
+		}
+	}
+	return health
+}
+
+// Close closes all DB handles which have been opened.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	var errs error
+	for _, name := range r.names {
+		e := r.entries[name]
+		e.mu.Lock()
+		if e.db != nil {
+			errs = multierr.Append(errs, e.db.Close())
+			e.db = nil
+		}
+		e.mu.Unlock()
+	}
+	return errs
+}
+
+// isClosed reports whether Registry has been closed.
+func (r *Registry) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
@@ -0,0 +1,79 @@
+package dbutil_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	r, err := dbutil.NewRegistry(cfgType, sqliteCfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{cfgSection, sessionSection}, r.Names()); diff != "" {
+		t.Error(diff)
+	}
+
+	db, err := r.DBContext(ctx, sessionSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := r.DBContext(ctx, sessionSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db != again {
+		t.Error("want: the same DB handle, got: another one")
+	}
+
+	// Only opened sections have health status.
+	health := r.Health(ctx)
+	if len(health) != 1 || health[sessionSection] != nil {
+		t.Errorf("health want: map[%s:<nil>], got: %v", sessionSection, health)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DBContext(ctx, cfgSection); !errors.Is(err, dbutil.ErrRegistryClosed) {
+		t.Errorf("want: %v, got: %v", dbutil.ErrRegistryClosed, err)
+	}
+	if err := db.PingContext(ctx); err == nil {
+		t.Error("want: error of closed DB, got: nil")
+	}
+}
+
+func TestRegistryErr(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	if _, err := dbutil.NewRegistry(cfgType, sqliteCfgPath, cfgSection, dummy); err == nil {
+		t.Error("want: error of unknown section, got: nil")
+	}
+
+	r, err := dbutil.NewRegistry(cfgType, sqliteCfgPath, cfgSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := r.DBContext(ctx, sessionSection); err == nil {
+		t.Error("want: error of section which is not loaded, got: nil")
+	}
+	if _, err := r.Config(dummy); err == nil {
+		t.Error("want: error of unknown section, got: nil")
+	}
+}
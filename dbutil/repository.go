@@ -0,0 +1,667 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/multierr"
+)
+
+const (
+	tagName = "db"
+	tagPK   = "pk" // option of the primary key. e.g. `db:"id,pk"`
+	// Options of time.Time, *time.Time or sql.NullTime set by the insert and update helpers
+	tagAutoCreate = "autocreate" // set on insert if it is zero. e.g. `db:"created_at,autocreate"`
+	tagAutoUpdate = "autoupdate" // set on insert if it is zero and on every update. e.g. `db:"updated_at,autoupdate"`
+	// Option of an integer for optimistic locking, which is checked and incremented by update. e.g. `db:"version,version"`
+	tagVersion = "version"
+
+	defaultBulkInsertSize = 1000
+)
+
+// ErrNoPrimaryKey is returned by methods of Repository which need the primary key if the struct has no pk option.
+var ErrNoPrimaryKey = errors.New("no primary key")
+
+// ConflictError is an error of update when the row was updated or deleted by others after it was read.
+// The version column of the row did not match.
+type ConflictError struct {
+	Table   string
+	ID      any // nil if the struct has no primary key
+	Version int64
+}
+
+// Error returns an error message which has the table, primary key and version.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s of id %v was updated or deleted after version %d", e.Table, e.ID, e.Version)
+}
+
+// TableNamer returns the table name of a struct of Repository.
+// The table name is the snake case of the struct name with "s" if it is not implemented. e.g. User -> users
+type TableNamer interface {
+	TableName() string
+}
+
+// tableMeta is a table of a struct.
+type tableMeta struct {
+	table    string
+	columns  []*columnMeta
+	pk       *columnMeta
+	version  *columnMeta
+	autoCols bool  // whether it has autocreate or autoupdate columns
+	optErr   error // error of an unknown option, which only NewRepository rejects
+}
+
+// columnMeta is a column of a field.
+type columnMeta struct {
+	name  string
+	index []int // index of the field for reflect.Value.FieldByIndex
+	pk    bool
+	// autocreate, autoupdate and version options
+	autoCreate bool
+	autoUpdate bool
+	version    bool
+}
+
+// tableMetas caches tables by types of structs.
+var tableMetas sync.Map
+
+// tableMetaOf returns the table of the struct.
+func tableMetaOf(typ reflect.Type) (*tableMeta, error) {
+	if m, ok := tableMetas.Load(typ); ok {
+		return m.(*tableMeta), nil
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", typ)
+	}
+
+	m := &tableMeta{table: tableName(typ)}
+	if err := m.addColumns(typ, nil); err != nil {
+		return nil, err
+	}
+	if len(m.columns) == 0 {
+		return nil, fmt.Errorf("%s has no columns", typ)
+	}
+
+	actual, _ := tableMetas.LoadOrStore(typ, m)
+	return actual.(*tableMeta), nil
+}
+
+// addColumns adds columns of exported fields of the struct in the same way as sqlx maps them.
+// Embedded structs without tags are flattened.
+func (m *tableMeta) addColumns(typ reflect.Type, index []int) error {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		tag := f.Tag.Get(tagName)
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		idx := append(append([]int(nil), index...), i)
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			if err := m.addColumns(f.Type, idx); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		col := &columnMeta{name: name, index: idx}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "":
+			case tagPK:
+				if m.pk != nil {
+					return fmt.Errorf("%s has multiple primary keys", typ)
+				}
+				col.pk = true
+				m.pk = col
+			case tagAutoCreate, tagAutoUpdate:
+				if !isTimeType(f.Type) {
+					return fmt.Errorf("%s option of %s must be time.Time, *time.Time or sql.NullTime", opt, name)
+				}
+				col.autoCreate = col.autoCreate || opt == tagAutoCreate
+				col.autoUpdate = col.autoUpdate || opt == tagAutoUpdate
+				m.autoCols = true
+			case tagVersion:
+				if m.version != nil {
+					return fmt.Errorf("%s has multiple versions", typ)
+				}
+				if !isIntType(f.Type) {
+					return fmt.Errorf("%s option of %s must be an integer", opt, name)
+				}
+				col.version = true
+				m.version = col
+			default:
+				// Options of other libraries do not break the helpers.
+				if m.optErr == nil {
+					m.optErr = fmt.Errorf("unknown option %q of %s", opt, name)
+				}
+			}
+		}
+		m.columns = append(m.columns, col)
+	}
+	return nil
+}
+
+// isTimeType reports whether columns of the type can be set by autocreate and autoupdate options.
+func isTimeType(typ reflect.Type) bool {
+	switch typ {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(&time.Time{}), reflect.TypeOf(sql.NullTime{}):
+		return true
+	}
+	return false
+}
+
+// isIntType reports whether columns of the type can be versions.
+func isIntType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// locationKey is a context key of the location of timestamps.
+type locationKey struct{}
+
+// WithLocation returns a context which makes the insert and update helpers
+// set columns of autocreate and autoupdate options to the current time in the location such as cfg.Location().
+// The helpers use the default Tz of Config without it.
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// defaultLocation is the location of the default Tz of Config, or UTC if it can not be loaded.
+var defaultLocation = func() *time.Location {
+	loc, err := time.LoadLocation(defaultTz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}()
+
+// nowIn returns the current time in the location of the context, or in defaultLocation without WithLocation.
+func nowIn(ctx context.Context) time.Time {
+	loc, _ := ctx.Value(locationKey{}).(*time.Location)
+	if loc == nil {
+		loc = defaultLocation
+	}
+	return time.Now().In(loc)
+}
+
+// setTimestamps sets now to autocreate and autoupdate columns of the row.
+// Columns are set if they are zero on insert, and autoupdate columns are always set on update.
+func (m *tableMeta) setTimestamps(row reflect.Value, now time.Time, insert bool) {
+	if !m.autoCols {
+		return
+	}
+
+	for _, col := range m.columns {
+		if !col.autoCreate && !col.autoUpdate {
+			continue
+		}
+		f := row.FieldByIndex(col.index)
+		if insert && !f.IsZero() || !insert && !col.autoUpdate {
+			continue
+		}
+
+		switch f.Interface().(type) {
+		case time.Time:
+			f.Set(reflect.ValueOf(now))
+		case *time.Time:
+			t := now
+			f.Set(reflect.ValueOf(&t))
+		case sql.NullTime:
+			f.Set(reflect.ValueOf(sql.NullTime{Time: now, Valid: true}))
+		}
+	}
+}
+
+// conflict returns *ConflictError of the row whose version did not match.
+func (m *tableMeta) conflict(row reflect.Value) error {
+	var id any
+	if m.pk != nil {
+		id = row.FieldByIndex(m.pk.index).Interface()
+	}
+	return &ConflictError{Table: m.table, ID: id, Version: intOf(row.FieldByIndex(m.version.index))}
+}
+
+// tableName returns the table name of the struct.
+func tableName(typ reflect.Type) string {
+	if namer, ok := reflect.New(typ).Interface().(TableNamer); ok {
+		return namer.TableName()
+	}
+
+	var b strings.Builder
+	for i, r := range typ.Name() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + "s"
+}
+
+// names returns names of the columns.
+func names(cols []*columnMeta) []string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.name
+	}
+	return names
+}
+
+// committer is a transaction which Repository begins and commits.
+type committer interface {
+	Transaction
+	Commit() error
+}
+
+// beginTx starts a transaction on DB handle such as *sqlx.DB, *DB and *Cluster.
+func beginTx(ctx context.Context, db sqlx.ExtContext) (committer, error) {
+	switch db := db.(type) {
+	case *DB:
+		return db.BeginTxx(ctx, nil)
+	case interface {
+		BeginTxx(context.Context, *sql.TxOptions) (*sqlx.Tx, error)
+	}:
+		return db.BeginTxx(ctx, nil)
+	}
+	return nil, fmt.Errorf("%T can not begin a transaction", db)
+}
+
+// Repository runs CRUD of the table of struct T.
+// Columns are db tags of fields, and the primary key has pk option. e.g. `db:"id,pk"`
+// Columns of autocreate and autoupdate options are set to the current time by Insert, BulkInsert and Update.
+// The column of version option is checked and incremented by Update for optimistic locking.
+type Repository[T any] struct {
+	db   sqlx.ExtContext
+	meta *tableMeta
+	loc  *time.Location
+}
+
+// NewRepository returns Repository of T on DB handle such as *sqlx.DB, *DB and *Cluster.
+// Timestamps are set in the location such as cfg.Location().
+func NewRepository[T any](db sqlx.ExtContext, loc *time.Location) (*Repository[T], error) {
+	if loc == nil {
+		return nil, errors.New("no location of timestamps")
+	}
+	meta, err := tableMetaOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	if meta.optErr != nil {
+		return nil, meta.optErr
+	}
+	return &Repository[T]{db: db, meta: meta, loc: loc}, nil
+}
+
+// WithTx returns Repository which runs queries on the transaction.
+// The transaction is rolled back if a method fails to write as the helpers do.
+func (r *Repository[T]) WithTx(tx Transaction) *Repository[T] {
+	return &Repository[T]{db: tx, meta: r.meta, loc: r.loc}
+}
+
+// inTx runs fn on the transaction of WithTx, or on a new transaction which is committed if fn succeeds.
+// The transaction is rolled back if fn fails, unless the helpers have already rolled it back.
+func (r *Repository[T]) inTx(ctx context.Context, fn func(tx Transaction) error) error {
+	var c committer
+	tx, ok := r.db.(Transaction)
+	if !ok {
+		var err error
+		if c, err = beginTx(ctx, r.db); err != nil {
+			return err
+		}
+		tx = c
+	}
+
+	tracked := &trackedTx{Transaction: tx}
+	if err := fn(tracked); err != nil {
+		if tracked.rolledBack {
+			return err
+		}
+		if rerr := tx.Rollback(); rerr != nil && !errors.Is(rerr, sql.ErrTxDone) {
+			return multierr.Append(err, rerr)
+		}
+		return err
+	}
+	if c == nil {
+		return nil
+	}
+	return c.Commit()
+}
+
+// trackedTx is a transaction which records whether it has been rolled back,
+// so that inTx does not roll back again and emit another rollback event of *Tx.
+type trackedTx struct {
+	Transaction
+	rolledBack bool
+}
+
+func (tx *trackedTx) Rollback() error {
+	tx.rolledBack = true
+	return tx.Transaction.Rollback()
+}
+
+// now returns the current time in the location of Repository.
+func (r *Repository[T]) now() time.Time {
+	return time.Now().In(r.loc)
+}
+
+// Table returns the table name.
+func (r *Repository[T]) Table() string {
+	return r.meta.table
+}
+
+// selectFrom returns Builder of SELECT of all columns.
+func (r *Repository[T]) selectFrom(columns ...string) *Builder {
+	b := NewBuilder(r.db.DriverName()).Append("SELECT ")
+	if len(columns) == 0 {
+		columns = names(r.meta.columns)
+	}
+	for i, col := range columns {
+		if i > 0 {
+			b.Append(", ")
+		}
+		b.Ident(col)
+	}
+	return b.Append(" FROM ").Ident(r.meta.table)
+}
+
+// FindByID returns the row of the primary key. It returns sql.ErrNoRows if there is no row.
+func (r *Repository[T]) FindByID(ctx context.Context, id any) (*T, error) {
+	if r.meta.pk == nil {
+		return nil, ErrNoPrimaryKey
+	}
+
+	query, args, err := r.selectFrom().Append(" WHERE ").Ident(r.meta.pk.name).Append(" = ?", id).Build()
+	if err != nil {
+		return nil, err
+	}
+	return GetContext[T](ctx, r.db, query, args)
+}
+
+// FindWhere returns rows which match all the conditions.
+func (r *Repository[T]) FindWhere(ctx context.Context, conds ...Condition) ([]*T, error) {
+	query, args, err := r.selectFrom().Where(conds...).Build()
+	if err != nil {
+		return nil, err
+	}
+	return SelectContext[T](ctx, r.db, query, args)
+}
+
+// Count returns the number of rows which match all the conditions.
+func (r *Repository[T]) Count(ctx context.Context, conds ...Condition) (int64, error) {
+	query, args, err := NewBuilder(r.db.DriverName()).
+		Append("SELECT COUNT(*) AS num FROM ").Ident(r.meta.table).Where(conds...).Build()
+	if err != nil {
+		return 0, err
+	}
+
+	row, err := GetContext[struct {
+		Num int64 `db:"num"`
+	}](ctx, r.db, query, args)
+	if err != nil {
+		return 0, err
+	}
+	return row.Num, nil
+}
+
+// insertColumns returns columns to insert.
+// The primary key is omitted if it is zero in all rows, so that the database generates it.
+func (r *Repository[T]) insertColumns(rows ...*T) []*columnMeta {
+	pk := r.meta.pk
+	if pk == nil {
+		return r.meta.columns
+	}
+	for _, row := range rows {
+		if !reflect.ValueOf(row).Elem().FieldByIndex(pk.index).IsZero() {
+			return r.meta.columns
+		}
+	}
+
+	cols := make([]*columnMeta, 0, len(r.meta.columns)-1)
+	for _, col := range r.meta.columns {
+		if !col.pk {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// Insert inserts the row, and sets the primary key generated by the database.
+// The row is not changed if it fails.
+func (r *Repository[T]) Insert(ctx context.Context, row *T) error {
+	// The row is set the timestamps and the primary key after the transaction is committed.
+	inserted := *row
+	cols := r.insertColumns(&inserted)
+	pk := r.meta.pk
+	if pk == nil || len(cols) == len(r.meta.columns) {
+		if _, err := r.BulkInsert(ctx, []*T{&inserted}); err != nil {
+			return err
+		}
+		*row = inserted
+		return nil
+	}
+
+	d, err := DialectOf(r.db.DriverName())
+	if err != nil {
+		return err
+	}
+	query, err := insertQuery(d, r.meta.table, names(cols))
+	if err != nil {
+		return err
+	}
+	r.meta.setTimestamps(reflect.ValueOf(&inserted).Elem(), r.now(), true)
+
+	id := reflect.ValueOf(&inserted).Elem().FieldByIndex(pk.index)
+	err = r.inTx(ctx, func(tx Transaction) error {
+		if d.SupportsReturning() {
+			quoted, err := d.QuoteIdent(pk.name)
+			if err != nil {
+				return err
+			}
+			rows, err := sqlx.NamedQueryContext(ctx, tx, query+" RETURNING "+quoted, &inserted)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			if !rows.Next() {
+				if err := rows.Err(); err != nil {
+					return err
+				}
+				return fmt.Errorf("no %s is returned by the insert into %s", pk.name, r.meta.table)
+			}
+			return rows.Scan(id.Addr().Interface())
+		}
+
+		result, err := namedExecTx(ctx, tx, query, &inserted)
+		if err != nil {
+			return err
+		}
+		last, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		switch id.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			id.SetInt(last)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			id.SetUint(uint64(last))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*row = inserted
+	return nil
+}
+
+// BulkInsert inserts the rows in a transaction by BulkInsertTxContext, and returns the number of inserted rows.
+// Statements have up to 1000 rows, and fewer rows if they have more bound variables than the driver accepts.
+// Primary keys generated by the database are not set to the rows.
+func (r *Repository[T]) BulkInsert(ctx context.Context, rows []*T) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	d, err := DialectOf(r.db.DriverName())
+	if err != nil {
+		return 0, err
+	}
+	query, err := insertQuery(d, r.meta.table, names(r.insertColumns(rows...)))
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = r.inTx(ctx, func(tx Transaction) error {
+		chunk := func(i, j int) []*T { return rows[i:j] }
+		num, err := BulkInsertTxContext(WithLocation(ctx, r.loc), tx, chunk, Query(query), 0, len(rows), defaultBulkInsertSize)
+		total = num
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Update updates all columns except autocreate ones of the row of the primary key, and returns the number of affected rows.
+// If the struct has a version column, the row is updated only if its version is not changed after it was read,
+// and the version is incremented. Otherwise it returns *ConflictError.
+func (r *Repository[T]) Update(ctx context.Context, row *T) (int64, error) {
+	if r.meta.pk == nil {
+		return 0, ErrNoPrimaryKey
+	}
+
+	d, err := DialectOf(r.db.DriverName())
+	if err != nil {
+		return 0, err
+	}
+	table, err := d.QuoteIdent(r.meta.table)
+	if err != nil {
+		return 0, err
+	}
+
+	var sets []string
+	for _, col := range r.meta.columns {
+		if col.pk || col.autoCreate && !col.autoUpdate {
+			continue
+		}
+		quoted, err := d.QuoteIdent(col.name)
+		if err != nil {
+			return 0, err
+		}
+		if col.version {
+			sets = append(sets, quoted+" = "+quoted+" + 1")
+			continue
+		}
+		sets = append(sets, quoted+" = :"+col.name)
+	}
+	if len(sets) == 0 {
+		return 0, fmt.Errorf("no columns to update %s", r.meta.table)
+	}
+	pk, err := d.QuoteIdent(r.meta.pk.name)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = :%s", table, strings.Join(sets, ", "), pk, r.meta.pk.name)
+	if r.meta.version != nil {
+		version, err := d.QuoteIdent(r.meta.version.name)
+		if err != nil {
+			return 0, err
+		}
+		query += fmt.Sprintf(" AND %s = :%s", version, r.meta.version.name)
+	}
+
+	// The row is set the timestamps and the version after the transaction is committed.
+	updated := *row
+	var num int64
+	err = r.inTx(ctx, func(tx Transaction) error {
+		num, err = UpdateRowTxContext(WithLocation(ctx, r.loc), tx, Query(query), &updated)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	*row = updated
+	return num, nil
+}
+
+// intOf returns the integer of the value.
+func intOf(v reflect.Value) int64 {
+	if v.CanInt() {
+		return v.Int()
+	}
+	return int64(v.Uint())
+}
+
+// UpdateRetry reads the row of the primary key, modifies it by fn and updates it.
+// It retries from reading the row up to attempts times while Update returns *ConflictError,
+// and returns the updated row. fn must not have side effects because it may be called more than once.
+// It does not retry on the transaction of WithTx, which is rolled back by the conflict.
+func (r *Repository[T]) UpdateRetry(ctx context.Context, id any, attempts int, fn func(row *T) error) (*T, error) {
+	if _, ok := r.db.(Transaction); ok {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		row, err := r.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(row); err != nil {
+			return nil, err
+		}
+
+		_, err = r.Update(ctx, row)
+		var conflict *ConflictError
+		if errors.As(err, &conflict) && attempt < attempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return row, nil
+	}
+}
+
+// Delete deletes the row of the primary key, and returns the number of affected rows.
+func (r *Repository[T]) Delete(ctx context.Context, id any) (int64, error) {
+	if r.meta.pk == nil {
+		return 0, ErrNoPrimaryKey
+	}
+
+	query, args, err := NewBuilder(r.db.DriverName()).
+		Append("DELETE FROM ").Ident(r.meta.table).Append(" WHERE ").Ident(r.meta.pk.name).Append(" = ?", id).Build()
+	if err != nil {
+		return 0, err
+	}
+	var num int64
+	err = r.inTx(ctx, func(tx Transaction) error {
+		num, err = UpdateTxContext(ctx, tx, query, args)
+		return err
+	})
+	return num, err
+}
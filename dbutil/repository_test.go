@@ -0,0 +1,519 @@
+package dbutil_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+// repoUser is users table without columns which have defaults.
+type repoUser struct {
+	ID     int    `db:"id,pk"`
+	Name   string `db:"name"`
+	Email  string `db:"email"`
+	Status int    `db:"status"`
+}
+
+func (repoUser) TableName() string { return "users" }
+
+// timeUser is users table with timestamps set by the insert and update helpers.
+type timeUser struct {
+	ID        int        `db:"id,pk"`
+	Name      string     `db:"name"`
+	Email     string     `db:"email"`
+	Status    int        `db:"status"`
+	CreatedAt time.Time  `db:"created_at,autocreate"`
+	UpdatedAt *time.Time `db:"updated_at,autoupdate"`
+}
+
+func (timeUser) TableName() string { return "users" }
+
+// versionUser is users table with a version column for optimistic locking.
+type versionUser struct {
+	ID      int    `db:"id,pk"`
+	Name    string `db:"name"`
+	Email   string `db:"email"`
+	Status  int    `db:"status"`
+	Version int    `db:"version,version"`
+}
+
+func (versionUser) TableName() string { return "users" }
+
+// noPKUser is users table without the primary key.
+type noPKUser struct {
+	Name string `db:"name"`
+}
+
+func (noPKUser) TableName() string { return "users" }
+
+func TestRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "repository"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo, err := dbutil.NewRepository[repoUser](db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.Table(); got != "users" {
+		t.Errorf("table want: users, got: %s", got)
+	}
+
+	u := &repoUser{Name: "inserted", Email: "inserted@example.com", Status: active}
+	if err := repo.Insert(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 2 {
+		t.Errorf("id want: 2, got: %d", u.ID)
+	}
+
+	got, err := repo.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *u {
+		t.Errorf("want: %v, got: %v", u, got)
+	}
+	if _, err := repo.FindByID(ctx, 100); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("want: sql.ErrNoRows, got: %v", err)
+	}
+
+	u.Name = "updated"
+	if num, err := repo.Update(ctx, u); err != nil || num != 1 {
+		t.Errorf("updated want: 1, got: %d, %v", num, err)
+	}
+
+	rows := []*repoUser{{Name: "bulk1", Status: non}, {Name: "bulk2", Status: non}}
+	if num, err := repo.BulkInsert(ctx, rows); err != nil || num != 2 {
+		t.Errorf("bulk inserted want: 2, got: %d, %v", num, err)
+	}
+
+	list, err := repo.FindWhere(ctx, dbutil.Cond("status = ?", active))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[1].Name != "updated" {
+		t.Errorf("want: 2 active users, got: %v", list)
+	}
+
+	if num, err := repo.Count(ctx, dbutil.CondIf(true, "status = ?", non)); err != nil || num != 2 {
+		t.Errorf("count want: 2, got: %d, %v", num, err)
+	}
+
+	if num, err := repo.Delete(ctx, u.ID); err != nil || num != 1 {
+		t.Errorf("deleted want: 1, got: %d, %v", num, err)
+	}
+	if num, err := repo.Count(ctx); err != nil || num != 3 {
+		t.Errorf("count want: 3, got: %d, %v", num, err)
+	}
+}
+
+func TestRepositoryTx(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "repository_tx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo, err := dbutil.NewRepository[repoUser](db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WithTx(tx).Insert(ctx, &repoUser{Name: "rollback"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if num, err := repo.Count(ctx); err != nil || num != 1 {
+		t.Errorf("count want: 1, got: %d, %v", num, err)
+	}
+}
+
+func TestRepositoryBulkInsert(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "repository_bulk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo, err := dbutil.NewRepository[repoUser](db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunks are inserted in a transaction, so the first chunk is rolled back by a duplicate key of the second.
+	const size = 2500
+	rows := make([]*repoUser, size)
+	for i := range rows {
+		rows[i] = &repoUser{ID: i + 2, Name: "bulk"}
+	}
+	rows[size-1].ID = 1
+	if _, err := repo.BulkInsert(ctx, rows); err == nil {
+		t.Error("want: error of a duplicate key, got: nil")
+	}
+	if num, err := repo.Count(ctx); err != nil || num != 1 {
+		t.Errorf("count want: 1, got: %d, %v", num, err)
+	}
+
+	rows[size-1].ID = size + 1
+	if num, err := repo.BulkInsert(ctx, rows); err != nil || num != size {
+		t.Errorf("bulk inserted want: %d, got: %d, %v", size, num, err)
+	}
+	if num, err := repo.Count(ctx); err != nil || num != size+1 {
+		t.Errorf("count want: %d, got: %d, %v", size+1, num, err)
+	}
+}
+
+func TestRepositoryRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "repository_rollback"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rec := &recorder{}
+	repo, err := dbutil.NewRepository[timeUser](dbutil.NewDB(db, rec.interceptor("a", nil)), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The helper rolls back the transaction by the duplicate key, and Repository does not roll it back again.
+	u := &timeUser{ID: 1, Name: "duplicate"}
+	if err := repo.Insert(ctx, u); err == nil {
+		t.Fatal("want: error of a duplicate key, got: nil")
+	}
+	if !u.CreatedAt.IsZero() || u.UpdatedAt != nil {
+		t.Errorf("want: the row is not changed, got: %v", u)
+	}
+
+	var rollbacks int
+	for _, ev := range rec.events {
+		if ev == "after a rollback" {
+			rollbacks++
+		}
+	}
+	if rollbacks != 1 {
+		t.Errorf("rollbacks want: 1, got: %d, %q", rollbacks, rec.events)
+	}
+}
+
+func TestRepositoryMeta(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "repository_meta"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	noPK, err := dbutil.NewRepository[noPKUser](db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := noPK.FindByID(ctx, 1); !errors.Is(err, dbutil.ErrNoPrimaryKey) {
+		t.Errorf("want: ErrNoPrimaryKey, got: %v", err)
+	}
+	if _, err := noPK.Delete(ctx, 1); !errors.Is(err, dbutil.ErrNoPrimaryKey) {
+		t.Errorf("want: ErrNoPrimaryKey, got: %v", err)
+	}
+
+	// The default table name is snake case of the struct name with "s".
+	user, err := dbutil.NewRepository[User](db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := user.Table(); got != "users" {
+		t.Errorf("table want: users, got: %s", got)
+	}
+
+	if _, err := dbutil.NewRepository[struct {
+		ID int `db:"id,unknown"`
+	}](db, time.UTC); err == nil {
+		t.Error("want: error of an unknown option, got: nil")
+	}
+	if _, err := dbutil.NewRepository[int](db, time.UTC); err == nil {
+		t.Error("want: error of not a struct, got: nil")
+	}
+	if _, err := dbutil.NewRepository[repoUser](db, nil); err == nil {
+		t.Error("want: error of no location, got: nil")
+	}
+}
+
+func TestRepositoryTimestamps(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	cfg := openSQLite(ctx, t, "repository_timestamps")
+	db, err := dbutil.OpenContext(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	loc, err := cfg.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := dbutil.NewRepository[timeUser](db, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &timeUser{Name: "timestamps"}
+	if err := repo.Insert(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if u.CreatedAt.IsZero() || u.UpdatedAt == nil || u.CreatedAt.Location() != loc {
+		t.Fatalf("want: timestamps in %s, got: %v, %v", loc, u.CreatedAt, u.UpdatedAt)
+	}
+
+	created, inserted := u.CreatedAt, *u.UpdatedAt
+	u.CreatedAt = created.Add(-time.Hour) // autocreate columns are not updated.
+	time.Sleep(10 * time.Millisecond)
+	if _, err := repo.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if !u.UpdatedAt.After(inserted) {
+		t.Errorf("updated_at want: after %v, got: %v", inserted, u.UpdatedAt)
+	}
+
+	got, err := repo.FindByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(created) || !got.UpdatedAt.Equal(*u.UpdatedAt) {
+		t.Errorf("want: %v, %v, got: %v, %v", created, u.UpdatedAt, got.CreatedAt, got.UpdatedAt)
+	}
+
+	// Zero timestamps are set by BulkInsertTxContext too, in the location of the context.
+	// The location is the default Tz of Config, which the config also has, without WithLocation.
+	for _, tt := range []struct {
+		ctx  context.Context
+		want *time.Location
+	}{
+		{ctx, loc},
+		{dbutil.WithLocation(ctx, time.UTC), time.UTC},
+	} {
+		rows := []*timeUser{{Name: "bulk"}}
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbutil.BulkInsertTxContext(tt.ctx, tx, func(i, j int) []*timeUser { return rows }, queryInsert, 1, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if rows[0].CreatedAt.Location().String() != tt.want.String() || rows[0].UpdatedAt == nil {
+			t.Errorf("want: timestamps in %s, got: %v, %v", tt.want, rows[0].CreatedAt, rows[0].UpdatedAt)
+		}
+	}
+
+	// Autoupdate columns are set by UpdateRowTxContext only if the row is updated.
+	for _, id := range []int{u.ID, 100} {
+		row := &timeUser{ID: id, Name: "row"}
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		num, err := dbutil.UpdateRowTxContext(dbutil.WithLocation(ctx, loc), tx, queryUpdateRow, row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if (row.UpdatedAt != nil) != (num == 1) {
+			t.Errorf("updated_at of id %d want: set only if updated, got: %v, %d rows", id, row.UpdatedAt, num)
+		}
+	}
+
+	// Unknown options such as ones of other libraries are rejected only by NewRepository.
+	type optUser struct {
+		Name  string `db:"name"`
+		Email string `db:"email,omitempty"`
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbutil.BulkInsertTxContext(ctx, tx, func(i, j int) []*optUser { return []*optUser{{Name: "opt"}} }, queryInsertOpt, 1, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbutil.NewRepository[optUser](db, loc); err == nil {
+		t.Error("want: error of an unknown option, got: nil")
+	}
+
+	if _, err := dbutil.NewRepository[struct {
+		CreatedAt string `db:"created_at,autocreate"`
+	}](db, time.UTC); err == nil {
+		t.Error("want: error of a non-time autocreate column, got: nil")
+	}
+}
+
+func TestRepositoryVersion(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	db, err := dbutil.OpenContext(ctx, openSQLite(ctx, t, "repository_version"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo, err := dbutil.NewRepository[versionUser](db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u1, err := repo.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2 := *u1
+
+	u1.Status = active
+	if _, err := repo.Update(ctx, u1); err != nil {
+		t.Fatal(err)
+	}
+	if u1.Version != 1 {
+		t.Errorf("version want: 1, got: %d", u1.Version)
+	}
+
+	// u2 was read before u1 was updated.
+	u2.Name = "stale"
+	var conflict *dbutil.ConflictError
+	if _, err := repo.Update(ctx, &u2); !errors.As(err, &conflict) {
+		t.Fatalf("want: ConflictError, got: %v", err)
+	}
+	if conflict.Table != "users" || conflict.ID != 1 || conflict.Version != 0 {
+		t.Errorf("want: users of id 1 and version 0, got: %+v", conflict)
+	}
+	if u2.Version != 0 {
+		t.Errorf("version of a conflict want: 0, got: %d", u2.Version)
+	}
+
+	cases := map[string]struct {
+		attempts  int
+		conflicts int
+		wantErr   bool
+	}{
+		"no conflicts":       {3, 0, false},
+		"retried":            {3, 2, false},
+		"too many conflicts": {2, 2, true},
+	}
+
+	for name, tt := range cases {
+		// Cases are not parallel because they update the same row.
+		t.Run(name, func(t *testing.T) {
+			conflicts := tt.conflicts
+			got, err := repo.UpdateRetry(ctx, 1, tt.attempts, func(u *versionUser) error {
+				if conflicts > 0 {
+					conflicts--
+					// Another update between reading and updating the row
+					if _, err := db.ExecContext(ctx, "UPDATE users SET version = version + 1 WHERE id = 1"); err != nil {
+						return err
+					}
+				}
+				u.Name = name
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error want: %t, got: %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if saved, err := repo.FindByID(ctx, 1); err != nil || *saved != *got || saved.Name != name {
+				t.Errorf("want: %v, got: %v, %v", got, saved, err)
+			}
+		})
+	}
+
+	// UpdateRowTxContext checks versions too, and does not change the row on conflicts.
+	type rowUser struct {
+		ID        int        `db:"id"`
+		Name      string     `db:"name"`
+		UpdatedAt *time.Time `db:"updated_at,autoupdate"`
+		Version   int        `db:"version,version"`
+	}
+	saved, err := repo.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []int{saved.Version - 1, saved.Version} {
+		row := &rowUser{ID: 1, Name: "row", Version: version}
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = dbutil.UpdateRowTxContext(dbutil.WithLocation(ctx, time.UTC), tx, queryUpdateVersion, row)
+		if version < saved.Version {
+			// The transaction is rolled back by the conflict.
+			if !errors.As(err, &conflict) || row.UpdatedAt != nil || row.Version != version {
+				t.Errorf("want: ConflictError without changes, got: %v, %+v", err, row)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if row.UpdatedAt == nil || row.Version != version+1 {
+			t.Errorf("want: updated_at and version %d, got: %+v", version+1, row)
+		}
+	}
+
+	if _, err := dbutil.NewRepository[struct {
+		Version string `db:"version,version"`
+	}](db, time.UTC); err == nil {
+		t.Error("want: error of a non-integer version, got: nil")
+	}
+}
@@ -0,0 +1,151 @@
+package dbutil
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBackoffInitial    = 500 * time.Millisecond
+	defaultBackoffMax        = 10 * time.Second
+	defaultBackoffMultiplier = 2
+)
+
+// Backoff is a policy to retry connecting to DB.
+// Zero values are replaced with defaults.
+type Backoff struct {
+	Initial    time.Duration // wait after the first failure, 500ms by default
+	Max        time.Duration // upper limit of waits, 10s by default
+	Multiplier float64       // growth rate of waits, 2 by default
+	Logger     *zap.Logger   // logger of failed attempts, nothing is logged by default
+}
+
+// NewDBRetryContext returns DB handle after retrying to connect to DB.
+func NewDBRetryContext(ctx context.Context, f *ConfigFile, b *Backoff) (*sqlx.DB, error) {
+	cfg, err := f.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := OpenRetryContext(ctx, cfg, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// OpenRetryContext returns DB handle like OpenContext,
+// but retries to ping DB with exponential backoff until the context is done.
+// It gives up at once if the error will not be solved by retrying. (e.g. wrong password, unknown database)
+func OpenRetryContext(ctx context.Context, cfg *Config, b *Backoff) (*sqlx.DB, error) {
+	b = b.withDefaults()
+
+	db, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := b.Initial
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return db, nil
+		}
+
+		if isPermanent(err) {
+			b.Logger.Error("failed to connect to DB", zap.Int("attempt", attempt), zap.Error(err))
+			return nil, multierr.Append(err, db.Close())
+		}
+		b.Logger.Warn("failed to connect to DB, retrying", zap.Int("attempt", attempt), zap.Duration("wait", wait), zap.Error(err))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, multierr.Combine(err, ctx.Err(), db.Close())
+		case <-timer.C:
+		}
+
+		if wait = time.Duration(float64(wait) * b.Multiplier); wait > b.Max {
+			wait = b.Max
+		}
+	}
+}
+
+// withDefaults returns a copy of Backoff which has no zero values.
+func (b *Backoff) withDefaults() *Backoff {
+	var c Backoff
+	if b != nil {
+		c = *b
+	}
+
+	if c.Initial <= 0 {
+		c.Initial = defaultBackoffInitial
+	}
+	if c.Max <= 0 {
+		c.Max = defaultBackoffMax
+	}
+	if c.Multiplier < 1 {
+		c.Multiplier = defaultBackoffMultiplier
+	}
+	if c.Logger == nil {
+		c.Logger = zap.NewNop()
+	}
+
+	return &c
+}
+
+// MySQL error numbers which are not solved by retrying.
+// See: https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+var permanentMySQLErrors = map[uint16]bool{
+	1044: true, // ER_DBACCESS_DENIED_ERROR
+	1045: true, // ER_ACCESS_DENIED_ERROR
+	1049: true, // ER_BAD_DB_ERROR
+	1698: true, // ER_ACCESS_DENIED_NO_PASSWORD_ERROR
+}
+
+// PostgreSQL error codes which are not solved by retrying.
+// See: https://www.postgresql.org/docs/current/errcodes-appendix.html
+var permanentPgSQLErrors = map[string]bool{
+	"28000": true, // invalid_authorization_specification
+	"28P01": true, // invalid_password
+	"3D000": true, // invalid_catalog_name
+}
+
+// SQLite error codes which are not solved by retrying.
+var permanentSQLiteErrors = map[sqlite3.ErrNo]bool{
+	sqlite3.ErrCantOpen: true,
+	sqlite3.ErrPerm:     true,
+	sqlite3.ErrAuth:     true,
+	sqlite3.ErrNotADB:   true,
+}
+
+// isPermanent reports whether err will not be solved by retrying.
+// Unknown errors are regarded as transient, such as refused connections while DB is starting.
+func isPermanent(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return permanentMySQLErrors[myErr.Number]
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return permanentPgSQLErrors[pgErr.Code]
+	}
+
+	var liteErr sqlite3.Error
+	if errors.As(err, &liteErr) {
+		return permanentSQLiteErrors[liteErr.Code]
+	}
+
+	return false
+}
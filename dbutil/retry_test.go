@@ -0,0 +1,100 @@
+package dbutil_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestOpenRetryContext(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		dsn      string
+		wantErr  bool
+		deadline bool
+		minLogs  int
+		maxLogs  int
+	}{
+		"success":   {"sqlite::memory:", false, false, 0, 0},
+		"transient": {fmt.Sprintf("mysql://%s:%s@127.0.0.1:1/%s", cfgUsername, cfgPassword, cfgDatabase), true, true, 2, 100},
+		"permanent": {"sqlite:///nonexistent/dir/test.sqlite3", true, false, 1, 1},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			cfg, err := dbutil.ParseDSN(tt.dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			core, logs := observer.New(zap.DebugLevel)
+			b := &dbutil.Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond, Logger: zap.New(core)}
+
+			db, err := dbutil.OpenRetryContext(ctx, cfg, b)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := db.Close(); err != nil {
+					t.Error(err)
+				}
+			} else if err == nil {
+				t.Fatal("want: error, got: nil")
+			}
+
+			if got := errors.Is(err, context.DeadlineExceeded); got != tt.deadline {
+				t.Errorf("deadline exceeded want: %t, got: %t (%v)", tt.deadline, got, err)
+			}
+			if n := logs.Len(); n < tt.minLogs || n > tt.maxLogs {
+				t.Errorf("logs want: %d to %d, got: %d", tt.minLogs, tt.maxLogs, n)
+			}
+		})
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"mysql access denied": {&mysql.MySQLError{Number: 1045}, true},
+		"mysql unknown db":    {fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1049}), true},
+		"mysql too many conn": {&mysql.MySQLError{Number: 1040}, false},
+		"pgsql password":      {&pgconn.PgError{Code: "28P01"}, true},
+		"pgsql unknown db":    {&pgconn.PgError{Code: "3D000"}, true},
+		"pgsql starting up":   {&pgconn.PgError{Code: "57P03"}, false},
+		"sqlite cant open":    {sqlite3.Error{Code: sqlite3.ErrCantOpen}, true},
+		"sqlite busy":         {sqlite3.Error{Code: sqlite3.ErrBusy}, false},
+		"bad conn":            {driver.ErrBadConn, false},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := dbutil.ExportIsPermanent(tt.err); got != tt.want {
+				t.Errorf("want: %t, got: %t", tt.want, got)
+			}
+		})
+	}
+}
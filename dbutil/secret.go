@@ -0,0 +1,179 @@
+package dbutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	Base64Secret = "base64" // the default secret provider
+	AESSecret    = "aes"    // the secret provider which encrypts passwords by AES-GCM
+
+	// SecretKeyEnv is the environment variable of a key of AESSecret.
+	// It is a key of 16, 24 or 32 bytes encoded in base64.
+	SecretKeyEnv = "DBUTIL_SECRET_KEY"
+)
+
+// SecretProvider encodes or encrypts passwords of DB config file, and decodes or decrypts them.
+// Passwords of providers other than base64 are written with the name of the provider such as "aes:...".
+type SecretProvider interface {
+	Encode(password string) (string, error)
+	Decode(secret string) (string, error)
+}
+
+var (
+	secretMu        sync.RWMutex
+	secretProviders = map[string]SecretProvider{
+		Base64Secret: base64Provider{},
+		AESSecret:    aesProvider{},
+	}
+)
+
+// RegisterSecretProvider makes a secret provider such as a KMS available by the name.
+// It panics if the name is empty, has ":" or is already registered.
+func RegisterSecretProvider(name string, p SecretProvider) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+
+	if name == "" || strings.Contains(name, ":") {
+		panic(fmt.Sprintf("dbutil: invalid secret provider name: %q", name))
+	}
+	if _, ok := secretProviders[name]; ok {
+		panic(fmt.Sprintf("dbutil: secret provider %q is already registered", name))
+	}
+	secretProviders[name] = p
+}
+
+// SecretProviders returns sorted names of the registered secret providers.
+func SecretProviders() []string {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+
+	names := make([]string, 0, len(secretProviders))
+	for name := range secretProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// secretProvider returns the secret provider of the name.
+func secretProvider(name string) (SecretProvider, error) {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+
+	p, ok := secretProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown secret provider: %q", name)
+	}
+	return p, nil
+}
+
+// encodeSecret returns the password encoded by the secret provider as DB config file has it.
+func encodeSecret(provider, password string) (string, error) {
+	if provider == "" {
+		provider = Base64Secret
+	}
+	p, err := secretProvider(provider)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := p.Encode(password)
+	if err != nil {
+		return "", err
+	}
+	if provider == Base64Secret {
+		return secret, nil
+	}
+	return provider + ":" + secret, nil
+}
+
+// decodeSecret returns the password of DB config file.
+// Passwords without the name of a provider are encoded in base64, which does not have ":".
+func decodeSecret(secret string) (string, error) {
+	provider, s, ok := strings.Cut(secret, ":")
+	if !ok {
+		provider, s = Base64Secret, secret
+	}
+	p, err := secretProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	return p.Decode(s)
+}
+
+// base64Provider encodes passwords in base64.
+type base64Provider struct{}
+
+func (base64Provider) Encode(password string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(password)), nil
+}
+
+func (base64Provider) Decode(secret string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(secret)
+	return string(b), err
+}
+
+// aesProvider encrypts passwords by AES-GCM with the key of SecretKeyEnv.
+// Secrets are a nonce and a ciphertext encoded in base64.
+type aesProvider struct{}
+
+// gcm returns AES-GCM by the key of SecretKeyEnv.
+func (aesProvider) gcm() (cipher.AEAD, error) {
+	env := os.Getenv(SecretKeyEnv)
+	if env == "" {
+		return nil, fmt.Errorf("%s is not set", SecretKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SecretKeyEnv, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SecretKeyEnv, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (p aesProvider) Encode(password string) (string, error) {
+	gcm, err := p.gcm()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(password), nil)), nil
+}
+
+func (p aesProvider) Decode(secret string) (string, error) {
+	gcm, err := p.gcm()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+	if len(b) < gcm.NonceSize() {
+		return "", errors.New("too short secret")
+	}
+	password, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
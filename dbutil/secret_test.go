@@ -0,0 +1,112 @@
+package dbutil_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+// reverseSecret is a secret provider which reverses passwords.
+type reverseSecret struct{}
+
+func (reverseSecret) Encode(password string) (string, error) { return reverse(password), nil }
+func (reverseSecret) Decode(secret string) (string, error)   { return reverse(secret), nil }
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func init() {
+	dbutil.RegisterSecretProvider("reverse", reverseSecret{})
+}
+
+// TestSecret is not parallel because it sets the environment variable of the key.
+func TestSecret(t *testing.T) {
+	t.Setenv(dbutil.SecretKeyEnv, base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")))
+
+	cases := map[string]struct {
+		provider string
+		prefix   string
+	}{
+		"default": {"", ""},
+		"base64":  {dbutil.Base64Secret, ""},
+		"aes":     {dbutil.AESSecret, "aes:"},
+		"custom":  {"reverse", "reverse:"},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			want := expectedConfig(t, mysqlDBType)
+			want.Password = "p@ss:word"
+
+			b, err := dbutil.MarshalConfig(cfgType, driverSection, want, tt.provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(b), want.Password) {
+				t.Fatalf("want: an encoded password, got: %s", b)
+			}
+			if !strings.Contains(string(b), "password = "+tt.prefix) {
+				t.Errorf("password want: prefix %q, got: %s", tt.prefix, b)
+			}
+
+			path := filepath.Join(t.TempDir(), "secret.dsn")
+			if err := os.WriteFile(path, b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := dbutil.ParseConfig(cfgType, path, driverSection)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Password != want.Password {
+				t.Errorf("password want: %s, got: %s", want.Password, got.Password)
+			}
+		})
+	}
+
+	if _, err := dbutil.MarshalConfig(cfgType, driverSection, expectedConfig(t, mysqlDBType), dummy); err == nil {
+		t.Error("want: error of an unknown provider, got: nil")
+	}
+
+	// Secrets of AES can not be decrypted by another key.
+	b, err := dbutil.MarshalConfig(cfgType, driverSection, expectedConfig(t, mysqlDBType), dbutil.AESSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secret.dsn")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(dbutil.SecretKeyEnv, base64.StdEncoding.EncodeToString([]byte("fedcba9876543210")))
+	if _, err := dbutil.ParseConfig(cfgType, path, driverSection); err == nil {
+		t.Error("want: error of another key, got: nil")
+	}
+	t.Setenv(dbutil.SecretKeyEnv, "")
+	if _, err := dbutil.MarshalConfig(cfgType, driverSection, expectedConfig(t, mysqlDBType), dbutil.AESSecret); err == nil {
+		t.Error("want: error without a key, got: nil")
+	}
+}
+
+func TestSecretProviders(t *testing.T) {
+	t.Parallel()
+
+	got := strings.Join(dbutil.SecretProviders(), ",")
+	if want := "aes,base64,reverse"; got != want {
+		t.Errorf("want: %s, got: %s", want, got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("want: panic of a registered name, got: nil")
+		}
+	}()
+	dbutil.RegisterSecretProvider(dbutil.Base64Secret, reverseSecret{})
+}
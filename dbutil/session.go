@@ -0,0 +1,227 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"go.uber.org/multierr"
+)
+
+// Statements are SQL statements separated by semicolons in DB config file.
+// Semicolons in quotes and comments do not separate statements. A list of statements is also accepted.
+type Statements []string
+
+// stringToStatementsHookFunc returns a decode hook which splits a string into Statements.
+func stringToStatementsHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data any) (any, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf(Statements{}) {
+			return data, nil
+		}
+		return splitStatements(data.(string)), nil
+	}
+}
+
+// splitStatements splits SQL by semicolons out of single quotes, double quotes, backquotes and comments (-- and /* */).
+// Quotes in quotes are escaped by doubling them.
+func splitStatements(sql string) Statements {
+	var stmts Statements
+	add := func(stmt string) {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	start := 0
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// A doubled quote closes and opens the quote again.
+			if end := strings.IndexByte(sql[i+1:], c); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+		case c == ';':
+			add(sql[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(sql) {
+		add(sql[start:])
+	}
+	return stmts
+}
+
+// sessionStatements returns statements which are run on every new connection.
+// MySQL ignores application_name and its max_execution_time only limits SELECT.
+func (cfg *Config) sessionStatements() ([]string, error) {
+	var stmts []string
+
+	switch cfg.Type {
+	case mysqlDBType:
+		if cfg.Tz != "" {
+			stmt, err := mysqlTimeZone(cfg.Tz)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt)
+		}
+		if cfg.StatementTimeout > 0 {
+			stmts = append(stmts, fmt.Sprintf("SET SESSION max_execution_time = %d", cfg.StatementTimeout.Milliseconds()))
+		}
+		if cfg.LockTimeout > 0 {
+			stmts = append(stmts, fmt.Sprintf("SET SESSION innodb_lock_wait_timeout = %d", ceilSeconds(cfg.LockTimeout)))
+		}
+		stmts = append(stmts, cfg.sessionVars("SET SESSION %s = %s")...)
+	case pgsqlDBType:
+		if cfg.Tz != "" {
+			stmts = append(stmts, fmt.Sprintf("SET TIME ZONE %s", quoteLiteral(cfg.Tz)))
+		}
+		if cfg.StatementTimeout > 0 {
+			stmts = append(stmts, fmt.Sprintf("SET statement_timeout = %d", cfg.StatementTimeout.Milliseconds()))
+		}
+		if cfg.LockTimeout > 0 {
+			stmts = append(stmts, fmt.Sprintf("SET lock_timeout = %d", cfg.LockTimeout.Milliseconds()))
+		}
+		if cfg.ApplicationName != "" {
+			stmts = append(stmts, fmt.Sprintf("SET application_name = %s", quoteLiteral(cfg.ApplicationName)))
+		}
+		stmts = append(stmts, cfg.sessionVars("SET %s = %s")...)
+	case sqliteDBType:
+		// SQLite has no lock timeout but waits for locks until busy_timeout.
+		if cfg.LockTimeout > 0 {
+			stmts = append(stmts, fmt.Sprintf("PRAGMA busy_timeout = %d", cfg.LockTimeout.Milliseconds()))
+		}
+		stmts = append(stmts, cfg.sessionVars("PRAGMA %s = %s")...)
+	}
+
+	return append(stmts, cfg.InitStatements...), nil
+}
+
+// mysqlTimeZone returns a statement which sets the named time zone to follow daylight saving time.
+// It sets the current offset instead if the time zone tables of MySQL are not loaded. (CONVERT_TZ returns NULL)
+// The offset is fixed while the connection lives, so set conn_max_lifetime for time zones which have daylight saving time.
+func mysqlTimeZone(tz string) (string, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return "", err
+	}
+
+	name := quoteLiteral(tz)
+	offset := quoteLiteral(time.Now().In(loc).Format("-07:00"))
+	return fmt.Sprintf("SET time_zone = IF(CONVERT_TZ('2000-01-01 00:00:00', '+00:00', %s) IS NULL, %s, %s)", name, offset, name), nil
+}
+
+// sessionVars returns statements which set Session in order of names.
+// Values are written as they are, so quote them in DB config file if necessary.
+func (cfg *Config) sessionVars(format string) []string {
+	names := make([]string, 0, len(cfg.Session))
+	for name := range cfg.Session {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stmts := make([]string, 0, len(names))
+	for _, name := range names {
+		stmts = append(stmts, fmt.Sprintf(format, name, cfg.Session[name]))
+	}
+	return stmts
+}
+
+// connector returns a connector which runs statements on every new connection.
+func (cfg *Config) connector(stmts []string) (driver.Connector, error) {
+	db, err := sql.Open(cfg.Driver, cfg.DataSrc)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+
+	var base driver.Connector = &dsnConnector{dsn: cfg.DataSrc, driver: drv}
+	if d, ok := drv.(driver.DriverContext); ok {
+		if base, err = d.OpenConnector(cfg.DataSrc); err != nil {
+			return nil, err
+		}
+	}
+
+	return &sessionConnector{Connector: base, stmts: stmts}, nil
+}
+
+// sessionConnector runs statements on every new connection.
+type sessionConnector struct {
+	driver.Connector
+	stmts []string
+}
+
+// Connect returns a new connection which has been initialized.
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		return nil, multierr.Append(errors.New("driver does not support ExecerContext"), conn.Close())
+	}
+
+	for _, stmt := range c.stmts {
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			return nil, multierr.Append(fmt.Errorf("failed to initialize session by %q: %w", stmt, err), conn.Close())
+		}
+	}
+
+	return conn, nil
+}
+
+// dsnConnector is a connector of a driver which does not implement driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect returns a new connection.
+func (c *dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver returns the underlying driver.
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// quoteLiteral returns a string literal of SQL.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// ceilSeconds returns seconds rounded up to at least 1.
+func ceilSeconds(d time.Duration) int64 {
+	sec := int64((d + time.Second - 1) / time.Second)
+	if sec < 1 {
+		return 1
+	}
+	return sec
+}
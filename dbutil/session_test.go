@@ -0,0 +1,139 @@
+package dbutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSessionStatements(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		cfg  *dbutil.Config
+		want []string
+	}{
+		"mysql": {
+			&dbutil.Config{Type: mysqlDBType, Tz: cfgTz, StatementTimeout: 5 * time.Second, LockTimeout: 1500 * time.Millisecond,
+				ApplicationName: dummy, Session: map[string]string{"sql_mode": "'TRADITIONAL'", "autocommit": "1"},
+				InitStatements: dbutil.Statements{"SET @dummy = 1"}},
+			[]string{
+				"SET time_zone = IF(CONVERT_TZ('2000-01-01 00:00:00', '+00:00', 'Asia/Tokyo') IS NULL, '+09:00', 'Asia/Tokyo')",
+				"SET SESSION max_execution_time = 5000",
+				"SET SESSION innodb_lock_wait_timeout = 2",
+				"SET SESSION autocommit = 1",
+				"SET SESSION sql_mode = 'TRADITIONAL'",
+				"SET @dummy = 1",
+			},
+		},
+		"pgsql": {
+			&dbutil.Config{Type: pgsqlDBType, Tz: cfgTz, StatementTimeout: 5 * time.Second, LockTimeout: time.Second,
+				ApplicationName: "it's", Session: map[string]string{"search_path": "app, public"}},
+			[]string{
+				"SET TIME ZONE 'Asia/Tokyo'",
+				"SET statement_timeout = 5000",
+				"SET lock_timeout = 1000",
+				"SET application_name = 'it''s'",
+				"SET search_path = app, public",
+			},
+		},
+		"sqlite": {
+			&dbutil.Config{Type: sqliteDBType, Tz: cfgTz, StatementTimeout: 5 * time.Second, LockTimeout: time.Second,
+				Session: map[string]string{"foreign_keys": "ON"}},
+			[]string{
+				"PRAGMA busy_timeout = 1000",
+				"PRAGMA foreign_keys = ON",
+			},
+		},
+		"none": {&dbutil.Config{Type: sqliteDBType}, nil},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := dbutil.ExportSessionStatements(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestOpenContextSession(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	t.Cleanup(cancel)
+
+	cfg, err := dbutil.ParseConfig(cfgType, sqliteCfgPath, sessionSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := dbutil.OpenContext(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	cases := map[string]struct {
+		query string
+		want  int
+	}{
+		"lock_timeout":    {`PRAGMA busy_timeout;`, 3000},
+		"session":         {`PRAGMA foreign_keys;`, 1},
+		"init_statements": {`SELECT COUNT(*) FROM sessions;`, 1},
+	}
+
+	for name, tt := range cases {
+		var got int
+		if err := db.GetContext(ctx, &got, tt.query); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("%s want: %d, got: %d", name, tt.want, got)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		sql  string
+		want dbutil.Statements
+	}{
+		"statements":   {"SET @a = 1; SET @b = 2;", dbutil.Statements{"SET @a = 1", "SET @b = 2"}},
+		"empty":        {" ; ", nil},
+		"single quote": {"SET @a = 'x;y'; SET @b = 'it''s;'", dbutil.Statements{"SET @a = 'x;y'", "SET @b = 'it''s;'"}},
+		"double quote": {`SELECT 1 AS "a;b"; SELECT 2`, dbutil.Statements{`SELECT 1 AS "a;b"`, "SELECT 2"}},
+		"backquote":    {"SELECT 1 AS `a;b`; SELECT 2", dbutil.Statements{"SELECT 1 AS `a;b`", "SELECT 2"}},
+		"comments":     {"SELECT 1 /* ; */; SELECT 2 -- ;\n; SELECT 3", dbutil.Statements{"SELECT 1 /* ; */", "SELECT 2 -- ;", "SELECT 3"}},
+		"unclosed":     {"SELECT 'a;b", dbutil.Statements{"SELECT 'a;b"}},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, dbutil.ExportSplitStatements(tt.sql)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
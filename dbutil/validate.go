@@ -0,0 +1,260 @@
+package dbutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"go.uber.org/multierr"
+)
+
+// sslModes are values which PostgreSQL accepts as sslmode.
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// protocols are values which MySQL accepts as protocol.
+var protocols = []string{"tcp", "unix"}
+
+// targetSessionAttrs are values which PostgreSQL accepts as target_session_attrs.
+var targetSessionAttrs = []string{"any", "read-write", "read-only", "primary", "standby", "prefer-standby"}
+
+// ConfigError is an error of a key in DB config.
+type ConfigError struct {
+	Path    string
+	Section string
+	Key     string
+	Err     error
+}
+
+// Error returns an error message which has the file path and section.
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		fmt.Fprintf(&b, "%s ", e.Path)
+	}
+	if e.Section != "" {
+		fmt.Fprintf(&b, "[%s] ", e.Section)
+	}
+	if e.Key != "" {
+		fmt.Fprintf(&b, "%s: ", e.Key)
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Validate returns all problems of DB config at once.
+func (cfg *Config) Validate() error {
+	var errs error
+	invalid := func(key, format string, a ...any) {
+		errs = multierr.Append(errs, &ConfigError{Key: key, Err: fmt.Errorf(format, a...)})
+	}
+
+	switch cfg.Type {
+	case "":
+		invalid("type", "is required")
+	case mysqlDBType, pgsqlDBType:
+		if cfg.Host == "" && cfg.Socket == "" && len(cfg.Hosts) == 0 {
+			invalid("host", "is required for %s unless socket or hosts is set", cfg.Type)
+		}
+		if cfg.Database == "" {
+			invalid("database", "is required for %s", cfg.Type)
+		}
+		if cfg.Username == "" {
+			invalid("username", "is required for %s", cfg.Type)
+		}
+		if cfg.Port == 0 && cfg.Socket == "" && !cfg.hasHostPorts() {
+			invalid("port", "must be between 1 and 65535")
+		}
+	case sqliteDBType:
+		if cfg.Database == "" {
+			invalid("database", "is required for %s", cfg.Type)
+		}
+	default:
+		invalid("type", "unsupported DB type %q", cfg.Type)
+	}
+
+	if cfg.Tz != "" {
+		if _, err := time.LoadLocation(cfg.Tz); err != nil {
+			invalid("tz", "%v", err)
+		}
+	}
+
+	durations := []struct {
+		key string
+		val time.Duration
+	}{
+		{"statement_timeout", cfg.StatementTimeout},
+		{"lock_timeout", cfg.LockTimeout},
+		{"read_timeout", cfg.ReadTimeout},
+		{"write_timeout", cfg.WriteTimeout},
+		{"conn_max_lifetime", cfg.ConnMaxLifetime},
+		{"conn_max_idle_time", cfg.ConnMaxIdleTime},
+	}
+	for _, d := range durations {
+		if d.val < 0 {
+			invalid(d.key, "must not be negative")
+		}
+	}
+
+	numbers := []struct {
+		key string
+		val int
+	}{
+		{"max_allowed_packet", cfg.MaxAllowedPacket},
+		{"max_open_conns", cfg.MaxOpenConns},
+		{"max_idle_conns", cfg.MaxIdleConns},
+	}
+	for _, n := range numbers {
+		if n.val < 0 {
+			invalid(n.key, "must not be negative")
+		}
+	}
+
+	if cfg.Type == mysqlDBType && cfg.Protocol != "" && !contains(protocols, cfg.Protocol) {
+		invalid("protocol", "must be one of %s", strings.Join(protocols, ", "))
+	}
+
+	if cfg.Type == mysqlDBType {
+		for k := range cfg.Params {
+			if key, ok := mysqlConfigParams[strings.ToLower(k)]; ok {
+				invalid("params."+k, "use %s instead", key)
+			}
+		}
+	}
+
+	if cfg.Type == pgsqlDBType && cfg.SSLMode != "" && !contains(sslModes, cfg.SSLMode) {
+		invalid("sslmode", "must be one of %s", strings.Join(sslModes, ", "))
+	}
+
+	if len(cfg.Hosts) > 0 {
+		if cfg.Type != pgsqlDBType {
+			invalid("hosts", "is only for %s", pgsqlDBType)
+		}
+		for _, addr := range cfg.Hosts {
+			if _, port, err := net.SplitHostPort(addr); err == nil {
+				if _, err := parsePort(port); err != nil {
+					invalid("hosts", "%v", err)
+				}
+			}
+		}
+	}
+
+	if cfg.TargetSessionAttrs != "" {
+		if cfg.Type != pgsqlDBType {
+			invalid("target_session_attrs", "is only for %s", pgsqlDBType)
+		} else if !contains(targetSessionAttrs, cfg.TargetSessionAttrs) {
+			invalid("target_session_attrs", "must be one of %s", strings.Join(targetSessionAttrs, ", "))
+		}
+	}
+
+	return errs
+}
+
+// hasHostPorts reports whether all of Hosts have ports.
+func (cfg *Config) hasHostPorts() bool {
+	if len(cfg.Hosts) == 0 {
+		return false
+	}
+	for _, addr := range cfg.Hosts {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// validateKeys returns problems of keys which can not be found in Config or can not be unmarshaled.
+// The invalid port is reset so that the other problems can be reported together.
+func validateKeys(sub *viper.Viper) error {
+	var errs error
+
+	known := configKeys()
+	for _, key := range sub.AllKeys() {
+		name, _, _ := strings.Cut(key, ".")
+		if !known[name] {
+			errs = multierr.Append(errs, &ConfigError{Key: key, Err: errors.New("unknown key")})
+		}
+	}
+
+	if sub.IsSet("port") {
+		port, err := cast.ToInt64E(sub.Get("port"))
+		if err != nil || port < 1 || port > 65535 {
+			errs = multierr.Append(errs, &ConfigError{Key: "port", Err: errors.New("must be between 1 and 65535")})
+			sub.Set("port", 0)
+		}
+	}
+
+	return errs
+}
+
+// configKeys returns keys of DB config file.
+func configKeys() map[string]bool {
+	keys := make(map[string]bool)
+
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		keys[name] = true
+	}
+
+	return keys
+}
+
+// appendConfigErrors appends errors of keys which have not been reported yet.
+func appendConfigErrors(errs, err error) error {
+	reported := make(map[string]bool)
+	for _, e := range multierr.Errors(errs) {
+		var cerr *ConfigError
+		if errors.As(e, &cerr) {
+			reported[cerr.Key] = true
+		}
+	}
+
+	for _, e := range multierr.Errors(err) {
+		var cerr *ConfigError
+		if errors.As(e, &cerr) && reported[cerr.Key] {
+			continue
+		}
+		errs = multierr.Append(errs, e)
+	}
+
+	return errs
+}
+
+// withLocation sets the file path and section to each error.
+func withLocation(err error, path, section string) error {
+	for _, e := range multierr.Errors(err) {
+		var cerr *ConfigError
+		if errors.As(e, &cerr) {
+			cerr.Path = path
+			cerr.Section = section
+		}
+	}
+	return err
+}
+
+// contains reports whether v is in list.
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
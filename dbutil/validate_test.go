@@ -0,0 +1,121 @@
+package dbutil_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/exaream/go-db/dbutil"
+	"go.uber.org/multierr"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		dbType string
+	}{
+		"mysql":  {mysqlDBType},
+		"pgsql":  {pgsqlDBType},
+		"sqlite": {sqliteDBType},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := expectedConfig(t, tt.dbType)
+			if err := cfg.Validate(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateErr(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		cfg  *dbutil.Config
+		keys []string
+	}{
+		"type":     {&dbutil.Config{}, []string{"type"}},
+		"unknown":  {&dbutil.Config{Type: dummy}, []string{"type"}},
+		"required": {&dbutil.Config{Type: mysqlDBType}, []string{"host", "database", "username", "port"}},
+		"sqlite":   {&dbutil.Config{Type: sqliteDBType}, []string{"database"}},
+		"protocol": {&dbutil.Config{Type: mysqlDBType, Host: mysqlHost, Database: cfgDatabase,
+			Username: cfgUsername, Port: mysqlPort, Protocol: dummy}, []string{"protocol"}},
+		"tz,sslmode": {&dbutil.Config{Type: pgsqlDBType, Host: pgsqlHost, Database: cfgDatabase,
+			Username: cfgUsername, Port: pgsqlPort, Tz: dummy, SSLMode: dummy}, []string{"tz", "sslmode"}},
+		"hosts": {&dbutil.Config{Type: mysqlDBType, Hosts: []string{mysqlHost + ":99999"}, Database: cfgDatabase,
+			Username: cfgUsername, Port: mysqlPort, TargetSessionAttrs: "primary"}, []string{"hosts", "hosts", "target_session_attrs"}},
+		"target_session_attrs": {&dbutil.Config{Type: pgsqlDBType, Hosts: []string{pgsqlHost + ":5432", pgsqlHost + ":5433"},
+			Database: cfgDatabase, Username: cfgUsername, TargetSessionAttrs: dummy}, []string{"target_session_attrs"}},
+		"socket": {&dbutil.Config{Type: mysqlDBType, Socket: "/var/run/mysqld/mysqld.sock", Database: cfgDatabase}, []string{"username"}},
+		"params": {&dbutil.Config{Type: mysqlDBType, Host: mysqlHost, Database: cfgDatabase, Username: cfgUsername, Port: mysqlPort,
+			Params: map[string]string{"parsetime": "true"}}, []string{"params.parsetime"}},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			errs := multierr.Errors(tt.cfg.Validate())
+			if len(errs) != len(tt.keys) {
+				t.Fatalf("len(errs) want: %d, got: %d (%v)", len(tt.keys), len(errs), errs)
+			}
+
+			for i, err := range errs {
+				var cerr *dbutil.ConfigError
+				if !errors.As(err, &cerr) {
+					t.Fatalf("want: *dbutil.ConfigError, got: %T", err)
+				}
+				if cerr.Key != tt.keys[i] {
+					t.Errorf("key want: %s, got: %s", tt.keys[i], cerr.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfigValidateErr(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		section string
+		keys    []string
+	}{
+		"no host":     {"no_host_section", []string{"host"}},
+		"port":        {"port_section", []string{"port"}},
+		"unknown key": {"unknown_key_section", []string{"passwd"}},
+		"multiple":    {"multiple_section", []string{"port", "password", "database", "tz", "sslmode"}},
+	}
+
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := dbutil.ParseConfig(cfgType, invalidCfgPath, tt.section)
+			errs := multierr.Errors(err)
+			if len(errs) != len(tt.keys) {
+				t.Fatalf("len(errs) want: %d, got: %d (%v)", len(tt.keys), len(errs), errs)
+			}
+
+			for i, err := range errs {
+				var cerr *dbutil.ConfigError
+				if !errors.As(err, &cerr) {
+					t.Fatalf("want: *dbutil.ConfigError, got: %T", err)
+				}
+				if cerr.Key != tt.keys[i] {
+					t.Errorf("key want: %s, got: %s", tt.keys[i], cerr.Key)
+				}
+				if msg := err.Error(); !strings.Contains(msg, invalidCfgPath) || !strings.Contains(msg, tt.section) {
+					t.Errorf("want: the path and section in the message, got: %s", msg)
+				}
+			}
+		})
+	}
+}
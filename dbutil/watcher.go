@@ -0,0 +1,224 @@
+package dbutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/multierr"
+)
+
+const (
+	defaultReloadTimeout = 10 * time.Second
+	reloadDebounce       = 100 * time.Millisecond
+	drainGrace           = 5 * time.Second        // time for callers to finish using the old DB handle
+	drainTimeout         = time.Minute            // maximum time to wait for connections in use
+	drainInterval        = 100 * time.Millisecond // interval to check connections in use
+)
+
+// ReloadFunc is called after DB config file has been reloaded.
+// cfg is the new DB config on success, and err is the reason on failure.
+type ReloadFunc func(cfg *Config, err error)
+
+// Watcher has DB handle which is updated when DB config file changes.
+// Get DB handle by DB on every use because the old one is closed a little after reloading.
+type Watcher struct {
+	reload   sync.Mutex   // serializes reloading
+	mu       sync.RWMutex // guards db and cfg
+	db       *sqlx.DB
+	cfg      *Config
+	file     *ConfigFile
+	fsw      *fsnotify.Watcher
+	onReload ReloadFunc
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// WatchConfig returns Watcher which opens DB handle now and reopens it when DB config file changes.
+// Changes of pool settings are applied in place, and the others open a new DB handle.
+// The old DB handle is drained: it is closed after a grace period for callers which got it before reloading,
+// and after its connections in use are released or a minute passes.
+// If the new DB config is invalid, the current DB handle is kept.
+// onReload may be nil.
+func WatchConfig(ctx context.Context, f *ConfigFile, onReload ReloadFunc) (*Watcher, error) {
+	if f.DSN != "" {
+		return nil, errors.New("can not watch DSN, use DB config file")
+	}
+
+	cfg, err := f.Parse()
+	if err != nil {
+		return nil, err
+	}
+	db, err := OpenContext(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, multierr.Append(err, db.Close())
+	}
+	// Watch the directory because some editors replace the file.
+	if err := fsw.Add(filepath.Dir(f.Path)); err != nil {
+		return nil, multierr.Combine(err, fsw.Close(), db.Close())
+	}
+
+	if onReload == nil {
+		onReload = func(*Config, error) {}
+	}
+
+	w := &Watcher{db: db, cfg: cfg, file: f, fsw: fsw, onReload: onReload, done: make(chan struct{})}
+	w.wg.Add(1)
+	go w.watch()
+
+	return w, nil
+}
+
+// DB returns the current DB handle.
+func (w *Watcher) DB() *sqlx.DB {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.db
+}
+
+// Config returns the current DB config.
+func (w *Watcher) Config() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cfg
+}
+
+// watch reloads DB config file on its events until Watcher is closed.
+// Events are debounced because writing a file causes several events.
+func (w *Watcher) watch() {
+	defer w.wg.Done()
+
+	debounce := time.NewTimer(0)
+	<-debounce.C
+	defer debounce.Stop()
+
+	path := filepath.Clean(w.file.Path)
+	for {
+		select {
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) == path && ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				debounce.Reset(reloadDebounce)
+			}
+		case <-debounce.C:
+			ctx, cancel := context.WithTimeout(context.Background(), defaultReloadTimeout)
+			w.Reload(ctx)
+			cancel()
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.onReload(nil, err)
+		}
+	}
+}
+
+// Reload parses DB config file, and updates DB handle if DB config has changed.
+func (w *Watcher) Reload(ctx context.Context) {
+	w.reload.Lock()
+	defer w.reload.Unlock()
+
+	cfg, err := w.file.Parse()
+	if err != nil {
+		w.onReload(nil, err)
+		return
+	}
+
+	w.mu.Lock()
+	old := w.db
+	switch {
+	case reflect.DeepEqual(w.cfg, cfg):
+		// Editors write the file several times.
+		w.mu.Unlock()
+		return
+	case reflect.DeepEqual(w.cfg.withoutPool(), cfg.withoutPool()):
+		cfg.setPool(old)
+		w.cfg = cfg
+		w.mu.Unlock()
+		w.onReload(cfg, nil)
+		return
+	}
+	w.mu.Unlock()
+
+	db, err := OpenContext(ctx, cfg)
+	if err != nil {
+		w.onReload(nil, err)
+		return
+	}
+
+	w.mu.Lock()
+	old, w.db, w.cfg = w.db, db, cfg
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go w.drain(old)
+
+	w.onReload(cfg, nil)
+}
+
+// drain closes the old DB handle after callers finish using it.
+// Closing sql.DB at once makes queries fail which start on it after reloading,
+// so it waits for the grace period and connections in use, or until Watcher is closed.
+func (w *Watcher) drain(old *sqlx.DB) {
+	defer w.wg.Done()
+
+	grace := time.NewTimer(drainGrace)
+	defer grace.Stop()
+	deadline := time.NewTimer(drainTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	select {
+	case <-w.done:
+	case <-grace.C:
+	wait:
+		for old.Stats().InUse > 0 {
+			select {
+			case <-w.done:
+				break wait
+			case <-deadline.C:
+				break wait
+			case <-ticker.C:
+			}
+		}
+	}
+
+	if err := old.Close(); err != nil {
+		w.onReload(nil, fmt.Errorf("failed to close the old DB handle: %w", err))
+	}
+}
+
+// withoutPool returns a copy of DB config which has no pool settings.
+func (cfg *Config) withoutPool() Config {
+	c := *cfg
+	c.MaxOpenConns = 0
+	c.MaxIdleConns = 0
+	c.ConnMaxLifetime = 0
+	c.ConnMaxIdleTime = 0
+	return c
+}
+
+// Close stops watching DB config file and closes DB handles including old ones being drained.
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.fsw.Close()
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return multierr.Append(err, w.db.Close())
+}
@@ -0,0 +1,121 @@
+package dbutil_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/exaream/go-db/dbutil"
+)
+
+const watcherSection = `[test_dbutil_section]
+type           = sqlite
+database       = %s
+max_open_conns = %d
+`
+
+// reload is a result of reloading DB config file.
+type reload struct {
+	cfg *dbutil.Config
+	err error
+}
+
+func TestWatchConfig(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "watch.dsn")
+	write := func(t *testing.T, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wait := func(t *testing.T, reloads <-chan reload) reload {
+		t.Helper()
+		select {
+		case r := <-reloads:
+			return r
+		case <-ctx.Done():
+			t.Fatal("want: reload, got: timeout")
+			return reload{}
+		}
+	}
+
+	db1 := filepath.Join(dir, "db1.sqlite3")
+	write(t, fmt.Sprintf(watcherSection, db1, 2))
+
+	reloads := make(chan reload, 10)
+	w, err := dbutil.WatchConfig(ctx, dbutil.NewConfigFile(cfgType, path, cfgSection), func(cfg *dbutil.Config, err error) {
+		reloads <- reload{cfg, err}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pool settings are applied in place.
+	db := w.DB()
+	write(t, fmt.Sprintf(watcherSection, db1, 5))
+	if r := wait(t, reloads); r.err != nil || r.cfg.MaxOpenConns != 5 {
+		t.Fatalf("reload want: max_open_conns = 5, got: %+v", r)
+	}
+	if w.DB() != db {
+		t.Error("DB handle want: the same one, got: a new one")
+	}
+	if n := db.Stats().MaxOpenConnections; n != 5 {
+		t.Errorf("max open connections want: 5, got: %d", n)
+	}
+
+	// Invalid DB config keeps the current DB handle.
+	write(t, fmt.Sprintf(watcherSection, db1, -1))
+	if r := wait(t, reloads); r.err == nil {
+		t.Fatal("reload want: error, got: nil")
+	}
+	if w.DB() != db {
+		t.Error("DB handle want: the same one, got: a new one")
+	}
+
+	// Connection settings open a new DB handle and close the old one.
+	db2 := filepath.Join(dir, "db2.sqlite3")
+	write(t, fmt.Sprintf(watcherSection, db2, 5))
+	if r := wait(t, reloads); r.err != nil || r.cfg.Database != db2 {
+		t.Fatalf("reload want: database = %s, got: %+v", db2, r)
+	}
+	if w.DB() == db {
+		t.Error("DB handle want: a new one, got: the same one")
+	}
+	if err := w.DB().PingContext(ctx); err != nil {
+		t.Error(err)
+	}
+
+	// The old DB handle is still usable by callers which got it before reloading.
+	if err := db.PingContext(ctx); err != nil {
+		t.Errorf("old DB handle want: open during the grace period, got: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PingContext(ctx); err == nil {
+		t.Error("old DB handle want: closed with Watcher, got: open")
+	}
+}
+
+func TestWatchConfigErr(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	if _, err := dbutil.WatchConfig(ctx, dbutil.NewDSNConfigFile("sqlite::memory:"), nil); err == nil {
+		t.Error("want: error of DSN, got: nil")
+	}
+	if _, err := dbutil.WatchConfig(ctx, dbutil.NewConfigFile(cfgType, invalidCfgPath, cfgSection), nil); err == nil {
+		t.Error("want: error of invalid DB config, got: nil")
+	}
+}
@@ -1,14 +1,19 @@
-// An example of a tool to operate MySQL and PostgreSQL.
+// An example of a tool to operate MySQL, PostgreSQL and SQLite.
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/exaream/go-db/dbutil"
 	"github.com/exaream/go-db/examples/example"
 	"go.uber.org/multierr"
+	"golang.org/x/term"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -23,22 +28,34 @@ const (
 
 // Arguments
 var (
-	app       = kingpin.New("example", "An example command made of Go to operate MySQL and PostgreSQL.")
-	typ       = app.Flag("type", "Set a config type.").Default("ini").String()
-	path      = app.Flag("path", "Set a config file path.").Default("mysql.dsn").String()
-	section   = app.Flag("section", "Set a config section name.").Default("example_section").String()
-	timeout   = app.Flag("timeout", "Set a timeout value. e.g. 5s").Default("10s").Duration()
-	id        = app.Flag("id", "Set an ID.").Default("0").Int()
-	beforeSts = app.Flag("before-sts", "Set a before status.").Default("0").Int()
-	afterSts  = app.Flag("after-sts", "Set a after status.").Default("0").Int()
-	setupFlg  = app.Flag("setup", "Set true if you want to initialize data.").Default("false").Bool()
+	app     = kingpin.New("example", "An example command made of Go to operate MySQL, PostgreSQL and SQLite.")
+	typ     = app.Flag("type", "Set a config type.").Default("ini").String()
+	path    = app.Flag("path", "Set a config file path.").Default("mysql.dsn").String()
+	section = app.Flag("section", "Set a config section name.").Default("example_section").String()
+	dsn     = app.Flag("dsn", "Set a connection URL or DSN instead of a config file.").String()
+	timeout = app.Flag("timeout", "Set a timeout value. e.g. 5s").Default("10s").Duration()
+
+	runCmd    = app.Command("run", "Update status of a user. (default)").Default()
+	id        = runCmd.Flag("id", "Set an ID.").Default("0").Int()
+	beforeSts = runCmd.Flag("before-sts", "Set a before status.").Default("0").Int()
+	afterSts  = runCmd.Flag("after-sts", "Set a after status.").Default("0").Int()
+	setupFlg  = runCmd.Flag("setup", "Set true if you want to initialize data.").Default("false").Bool()
+
+	configCmd = app.Command("config", "Generate a config section. Use --type and --section for its format and name.")
+	genFlags  = newConfigFlags(configCmd)
+
+	healthCmd = app.Command("health", "Check health of DB and show pool statistics and server facts.")
+	probe     = healthCmd.Flag("probe", "Set a probe query.").Default("SELECT 1").String()
 )
 
+var command string
+
 func init() {
 	app.Version(version)
 
 	// Parse command's arguments.
-	if _, err := app.Parse(os.Args[1:]); err != nil {
+	var err error
+	if command, err = app.Parse(os.Args[1:]); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -47,7 +64,26 @@ func init() {
 func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
+
+	if command == configCmd.FullCommand() {
+		if err := generateConfig(ctx, genFlags, *typ, *section); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	cfg := dbutil.NewConfigFile(*typ, *path, *section)
+	if *dsn != "" {
+		cfg = dbutil.NewDSNConfigFile(*dsn)
+	}
+
+	if command == healthCmd.FullCommand() {
+		if err := checkHealth(ctx, cfg, *probe); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	cond := example.NewCond(*id, *beforeSts, *afterSts)
 
 	// Generate initial data.
@@ -69,3 +105,187 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// configFlags are flags of config command.
+type configFlags struct {
+	dbType      *string
+	host        *string
+	port        *uint16
+	database    *string
+	username    *string
+	password    *string
+	secret      *string
+	tz          *string
+	sslmode     *string
+	output      *string
+	interactive *bool
+	validate    *bool
+}
+
+// newConfigFlags returns flags of config command.
+func newConfigFlags(cmd *kingpin.CmdClause) *configFlags {
+	return &configFlags{
+		dbType:      cmd.Flag("db-type", "Set a DB type.").Default("mysql").Enum("mysql", "pgsql", "sqlite"),
+		host:        cmd.Flag("host", "Set a host.").String(),
+		port:        cmd.Flag("port", "Set a port. (3306 for MySQL and 5432 for PostgreSQL by default)").Uint16(),
+		database:    cmd.Flag("database", "Set a database name or a file path for SQLite.").String(),
+		username:    cmd.Flag("username", "Set a user name.").String(),
+		password:    cmd.Flag("password", "Set a plain password. It is encoded by --secret-provider.").String(),
+		secret:      cmd.Flag("secret-provider", "Set a secret provider of the password. aes uses a key of "+dbutil.SecretKeyEnv+".").Default(dbutil.Base64Secret).Enum(dbutil.SecretProviders()...),
+		tz:          cmd.Flag("tz", "Set a time zone.").Default("Asia/Tokyo").String(),
+		sslmode:     cmd.Flag("sslmode", "Set sslmode for PostgreSQL.").Default("disable").String(),
+		output:      cmd.Flag("output", "Set an output file path. Print it if it is empty.").String(),
+		interactive: cmd.Flag("interactive", "Set true if you want to be asked values which are not set.").Default("false").Bool(),
+		validate:    cmd.Flag("validate", "Set true if you want to connect to DB by the generated config.").Default("false").Bool(),
+	}
+}
+
+// config returns DB config by flags.
+func (f *configFlags) config() *dbutil.Config {
+	cfg := &dbutil.Config{
+		Type:     *f.dbType,
+		Host:     *f.host,
+		Port:     *f.port,
+		Database: *f.database,
+		Username: *f.username,
+		Password: *f.password,
+		Tz:       *f.tz,
+	}
+
+	switch cfg.Type {
+	case "mysql":
+		cfg.Protocol = "tcp"
+		if cfg.Port == 0 {
+			cfg.Port = 3306
+		}
+	case "pgsql":
+		cfg.Protocol = "tcp"
+		cfg.SSLMode = *f.sslmode
+		if cfg.Port == 0 {
+			cfg.Port = 5432
+		}
+	}
+
+	return cfg
+}
+
+// ask asks values which are not set yet.
+// The password is not echoed if it is read from a terminal.
+func ask(r *bufio.Reader, w io.Writer, cfg *dbutil.Config) error {
+	questions := []struct {
+		label  string
+		val    *string
+		skip   bool
+		secret bool
+	}{
+		{"host", &cfg.Host, cfg.Type == "sqlite", false},
+		{"database", &cfg.Database, false, false},
+		{"username", &cfg.Username, cfg.Type == "sqlite", false},
+		{"password", &cfg.Password, cfg.Type == "sqlite", true},
+	}
+
+	for _, q := range questions {
+		if q.skip || *q.val != "" {
+			continue
+		}
+		fmt.Fprintf(w, "%s: ", q.label)
+
+		if fd := int(os.Stdin.Fd()); q.secret && term.IsTerminal(fd) {
+			b, err := term.ReadPassword(fd)
+			fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+			*q.val = string(b)
+			continue
+		}
+
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		*q.val = strings.TrimSpace(line)
+	}
+
+	return nil
+}
+
+// generateConfig writes a config section by flags.
+func generateConfig(ctx context.Context, f *configFlags, typ, section string) (rerr error) {
+	cfg := f.config()
+	if *f.interactive {
+		if err := ask(bufio.NewReader(os.Stdin), os.Stderr, cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	b, err := dbutil.MarshalConfig(typ, section, cfg, *f.secret)
+	if err != nil {
+		return err
+	}
+
+	path := *f.output
+	if path == "" {
+		if _, err := os.Stdout.Write(b); err != nil {
+			return err
+		}
+		if !*f.validate {
+			return nil
+		}
+
+		// Write a temporary file to validate the printed config.
+		dir, err := os.MkdirTemp("", "example")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			rerr = multierr.Append(rerr, os.RemoveAll(dir))
+		}()
+		path = filepath.Join(dir, "config."+typ)
+	}
+
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return err
+	}
+	if !*f.validate {
+		return nil
+	}
+
+	// Round trip: parse the generated config and connect to DB.
+	db, err := dbutil.NewDBContext(ctx, dbutil.NewConfigFile(typ, path, section))
+	if err != nil {
+		return fmt.Errorf("failed to validate the generated config: %w", err)
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "succeeded to connect to DB by the generated config.")
+
+	return nil
+}
+
+// checkHealth shows health of DB.
+func checkHealth(ctx context.Context, cfg *dbutil.ConfigFile, probe string) (rerr error) {
+	db, err := dbutil.NewDBContext(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		rerr = multierr.Append(rerr, db.Close())
+	}()
+
+	h := dbutil.NewHealthChecker(db)
+	h.ProbeQuery = probe
+	health, err := h.Check(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(health)
+
+	return nil
+}
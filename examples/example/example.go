@@ -3,6 +3,7 @@ package example
 
 import (
 	"context"
+	_ "embed"
 	"fmt"
 	"time"
 
@@ -14,30 +15,51 @@ const (
 	layout = "2006-01-02 15:04:05"
 
 	// Driver
-	mysqlDriver = "mysql"
-	pgsqlDriver = "pgx"
+	mysqlDriver  = "mysql"
+	pgsqlDriver  = "pgx"
+	sqliteDriver = "sqlite3"
+
+	// Table
+	tableUsers = "users"
 
 	// SQL
-	querySelect = `SELECT id, name, status, created_at, updated_at FROM users WHERE id = :id AND status = :status;`
+	querySelect = `SELECT id, name, status, created_at, updated_at, version FROM users WHERE id = :id AND status = :status;`
 	queryInsert = `INSERT INTO users (name, email, status, created_at, updated_at) 
 VALUES (:name, :email, :status, :created_at, :updated_at);`
-	queryUpdate = `UPDATE users SET status = :afterSts, updated_at = NOW() WHERE id = :id AND status = :beforeSts;`
+	queryUpdate = `UPDATE users SET status = :afterSts, updated_at = :updated_at, version = version + 1 
+WHERE id = :id AND status = :beforeSts AND version = :version;`
 )
 
-var queryTruncateTbls = map[string]string{
-	mysqlDriver: `TRUNCATE TABLE users;`,
-	pgsqlDriver: `TRUNCATE TABLE users RESTART IDENTITY;`,
+// SQLite has no setup container, so the tool creates the table by itself.
+// Tests of dbutil create the table by the same file.
+//
+//go:embed schema/sqlite.sql
+var queryCreateTblSQLite string
+
+var queryCreateTbls = map[string]string{
+	sqliteDriver: queryCreateTblSQLite,
 }
 
 // Schema of users table
 // Please use exported struct and fields because dbutil package handle these. (rows.StructScan)
+// Timestamps are set and the version is checked by the insert and update helpers of dbutil.
 type User struct {
 	ID        int        `db:"id"`
 	Name      string     `db:"name"`
 	Email     string     `db:"email"`
 	Status    int        `db:"status"`
-	CreatedAt *time.Time `db:"created_at"`
-	UpdatedAt *time.Time `db:"updated_at"`
+	CreatedAt *time.Time `db:"created_at,autocreate"`
+	UpdatedAt *time.Time `db:"updated_at,autoupdate"`
+	Version   int        `db:"version,version"`
+}
+
+// statusUpdate has args of queryUpdate.
+type statusUpdate struct {
+	ID        int        `db:"id"`
+	BeforeSts int        `db:"beforeSts"`
+	AfterSts  int        `db:"afterSts"`
+	UpdatedAt *time.Time `db:"updated_at,autoupdate"`
+	Version   int        `db:"version,version"`
 }
 
 // User's stringer.
@@ -21,6 +21,10 @@ func TestMain(m *testing.M) {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	if err := initDB(sqliteDBType, beforeSQLPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	m.Run()
 }
 
@@ -30,8 +34,9 @@ func TestRun(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 1},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 1},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 1},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 1},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 1},
 	}
 
 	for name, tt := range cases {
@@ -62,8 +67,9 @@ func TestRunErr(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 0},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 0},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 0},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 0},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 0},
 	}
 
 	for name, tt := range cases {
@@ -91,8 +97,9 @@ func TestNewExecutor(t *testing.T) {
 		dbType string
 		path   string
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath},
+		"mysql":  {mysqlDBType, mysqlCfgPath},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath},
+		"sqlite": {sqliteDBType, sqliteCfgPath},
 	}
 
 	for name, tt := range cases {
@@ -3,34 +3,48 @@ package example
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/exaream/go-db/dbutil"
-	"github.com/jmoiron/sqlx"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
-// Executor has logger and db.
+const slowQueryThreshold = 200 * time.Millisecond
+
+// Executor has logger, db and the location of timestamps.
 type Executor struct {
-	Logger *zap.Logger
-	DB     *sqlx.DB
+	Logger   *zap.Logger
+	DB       *dbutil.DB
+	Location *time.Location
 }
 
-// NewExecutor returns Executor.
+// NewExecutor returns Executor after connecting to DB.
 func NewExecutor(ctx context.Context, cfg *dbutil.ConfigFile) (*Executor, error) {
-	db, err := dbutil.NewDBContext(ctx, cfg)
+	Logger, err := zap.NewDevelopment()
 	if err != nil {
 		return nil, err
 	}
 
-	Logger, err := zap.NewDevelopment()
+	c, err := cfg.Parse()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := c.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for DB to start until the context is done. (e.g. right after `docker compose up`)
+	db, err := dbutil.NewDBRetryContext(ctx, cfg, &dbutil.Backoff{Logger: Logger})
 	if err != nil {
 		return nil, err
 	}
 
 	return &Executor{
-		Logger: Logger,
-		DB:     db,
+		Logger:   Logger,
+		DB:       dbutil.NewDB(db, &dbutil.QueryLogger{Logger: Logger, SlowThreshold: slowQueryThreshold}),
+		Location: loc,
 	}, nil
 }
 
@@ -50,21 +64,31 @@ func (ex *Executor) prepare(ctx context.Context, cond *Cond) error {
 }
 
 // exec runs UPDATE and SELECT clause on the same transaction.
+// UPDATE returns *dbutil.ConflictError if the row was updated by others after it was read.
 func (ex *Executor) exec(ctx context.Context, cond *Cond) error {
-	tx := ex.DB.MustBeginTx(ctx, nil)
+	tx, err := ex.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
 
-	args := map[string]any{"id": cond.id, "beforeSts": cond.beforeSts, "afterSts": cond.afterSts}
-	num, err := dbutil.UpdateTxContext(ctx, tx, queryUpdate, args)
+	args := map[string]any{"id": cond.id, "status": cond.beforeSts}
+	rows, err := dbutil.SelectTxContext[User](ctx, tx, querySelect, args)
 	if err != nil {
 		return err
 	}
 
-	if num < 1 {
-		return multierr.Append(errors.New("there is no affected rows"), tx.Rollback())
+	if len(rows) < 1 {
+		return multierr.Append(errors.New("there is no target rows"), tx.Rollback())
+	}
+
+	// updated_at is set in Tz of the config, and the version is checked for optimistic locking.
+	row := &statusUpdate{ID: cond.id, BeforeSts: cond.beforeSts, AfterSts: cond.afterSts, Version: rows[0].Version}
+	if _, err := dbutil.UpdateRowTxContext(dbutil.WithLocation(ctx, ex.Location), tx, queryUpdate, row); err != nil {
+		return err
 	}
 
 	args = map[string]any{"id": cond.id, "status": cond.afterSts}
-	rows, err := dbutil.SelectTxContext[User](ctx, tx, querySelect, args)
+	rows, err = dbutil.SelectTxContext[User](ctx, tx, querySelect, args)
 	if err != nil {
 		return err
 	}
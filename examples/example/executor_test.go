@@ -15,8 +15,9 @@ func TestPrepare(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 1},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 1},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 1},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 1},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 1},
 	}
 
 	for name, tt := range cases {
@@ -50,8 +51,9 @@ func TestPrepareErr(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 0},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 0},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 0},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 0},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 0},
 	}
 
 	for name, tt := range cases {
@@ -85,8 +87,9 @@ func TestExec(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 1},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 1},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 1},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 1},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 1},
 	}
 
 	for name, tt := range cases {
@@ -122,8 +125,9 @@ func TestExecErr(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 0},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 0},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 0},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 0},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 0},
 	}
 
 	for name, tt := range cases {
@@ -157,8 +161,9 @@ func TestTeardown(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 1},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 1},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 1},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 1},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 1},
 	}
 
 	for name, tt := range cases {
@@ -193,8 +198,9 @@ func TestTeardownErr(t *testing.T) {
 		path   string
 		id     int
 	}{
-		"mysql": {mysqlDBType, mysqlCfgPath, 0},
-		"pgsql": {pgsqlDBType, pgsqlCfgPath, 0},
+		"mysql":  {mysqlDBType, mysqlCfgPath, 0},
+		"pgsql":  {pgsqlDBType, pgsqlCfgPath, 0},
+		"sqlite": {sqliteDBType, sqliteCfgPath, 0},
 	}
 
 	for name, tt := range cases {
@@ -21,25 +21,28 @@ const (
 
 	// Config MySQL
 	mysqlDBType = "mysql"
-	mysqlDriver = "mysql"
 
 	// Config PostgreSQL
 	pgsqlDBType = "pgsql"
-	pgsqlDriver = "pgx"
+
+	// Config SQLite
+	sqliteDBType = "sqlite"
+	sqliteDriver = "sqlite3"
+
+	// Table
+	tableUsers = "users"
 )
 
 var (
 	testDir       = string(filepath.Separator) + filepath.Join("go", "src", "work", "examples", "example", "testdata")
 	mysqlCfgPath  = filepath.Join(testDir, "mysql.dsn")
 	pgsqlCfgPath  = filepath.Join(testDir, "pgsql.dsn")
+	sqliteCfgPath = filepath.Join(testDir, "sqlite.dsn")
 	beforeSQLPath = filepath.Join(testDir, "before_update.sql")
 	afterSQLPath  = filepath.Join(testDir, "after_update.sql")
 
-	// Query
-	queryTruncateTbls = map[string]string{
-		mysqlDriver: `TRUNCATE TABLE users;`,
-		pgsqlDriver: `TRUNCATE TABLE users RESTART IDENTITY;`,
-	}
+	// SQLite has no setup container, so tests create the table by themselves.
+	sqliteDDLPath = filepath.Join(testDir, "..", "schema", "sqlite.sql")
 )
 
 // prepareDB prepares initial data
@@ -62,13 +65,15 @@ func prepareDB(t *testing.T, dbType, sqlPath string) {
 		t.Fatal(err)
 	}
 
-	args := make(map[string]any)
-	queryTruncateTbl := queryTruncateTbls[db.DriverName()]
-	_, err = sqlx.NamedExecContext(ctx, db, queryTruncateTbl, args)
-	if err != nil {
+	if err := createTable(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := truncateTable(ctx, db); err != nil {
 		t.Fatal(err)
 	}
 
+	args := make(map[string]any)
 	_, err = sqlx.NamedExecContext(ctx, db, string(query), args)
 	if err != nil {
 		t.Fatal(err)
@@ -92,14 +97,36 @@ func initDB(dbType, sqlPath string) error {
 		return err
 	}
 
-	args := make(map[string]any)
-	queryTruncateTbl := queryTruncateTbls[db.DriverName()]
-	if _, err = sqlx.NamedExecContext(ctx, db, queryTruncateTbl, args); err != nil {
+	if err := createTable(ctx, db); err != nil {
 		return err
 	}
-	if _, err = sqlx.NamedExecContext(ctx, db, string(query), args); err != nil {
+
+	if err := truncateTable(ctx, db); err != nil {
+		return err
+	}
+	if _, err = sqlx.NamedExecContext(ctx, db, string(query), make(map[string]any)); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// truncateTable deletes all rows of users table and resets its identity by the dialect of the driver.
+func truncateTable(ctx context.Context, db *sqlx.DB) error {
+	return dbutil.TruncateTableContext(ctx, db, tableUsers)
+}
+
+// createTable creates users table only for SQLite.
+func createTable(ctx context.Context, db *sqlx.DB) error {
+	if db.DriverName() != sqliteDriver {
+		return nil
+	}
+
+	query, err := os.ReadFile(sqliteDDLPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, string(query))
+	return err
+}
@@ -2,7 +2,6 @@ package example
 
 import (
 	"context"
-	"time"
 
 	"github.com/bxcodec/faker/v3"
 	"github.com/exaream/go-db/dbutil"
@@ -12,7 +11,16 @@ import (
 
 // Setup generates initial data.
 func Setup(ctx context.Context, cfg *dbutil.ConfigFile, min, max, chunkSize int) (total int64, err error) {
-	db, err := dbutil.NewDBContext(ctx, cfg)
+	c, err := cfg.Parse()
+	if err != nil {
+		return 0, err
+	}
+	loc, err := c.Location()
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := dbutil.OpenContext(ctx, c)
 	if err != nil {
 		return 0, err
 	}
@@ -23,20 +31,26 @@ func Setup(ctx context.Context, cfg *dbutil.ConfigFile, min, max, chunkSize int)
 		}
 	}()
 
-	tx := db.MustBeginTx(ctx, nil)
-	queryTruncateTbl := queryTruncateTbls[db.DriverName()]
+	if queryCreateTbl, ok := queryCreateTbls[db.DriverName()]; ok {
+		if _, err := db.ExecContext(ctx, queryCreateTbl); err != nil {
+			return 0, err
+		}
+	}
 
-	if _, err := tx.ExecContext(ctx, queryTruncateTbl); err != nil {
+	tx := db.MustBeginTx(ctx, nil)
+	if err := dbutil.TruncateTableContext(ctx, tx, tableUsers); err != nil {
 		return 0, multierr.Append(err, tx.Rollback())
 	}
 
-	total, err = dbutil.BulkInsertTxContext(ctx, tx, fakeUsers, queryInsert, min, max, chunkSize)
+	// BulkInsertTxContext rolls back the transaction by itself on errors.
+	// Timestamps are set in Tz of the config.
+	total, err = dbutil.BulkInsertTxContext(dbutil.WithLocation(ctx, loc), tx, fakeUsers, queryInsert, min, max, chunkSize)
 	if err != nil {
-		return 0, multierr.Append(err, tx.Rollback())
+		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return 0, multierr.Append(err, tx.Rollback())
+		return 0, err
 	}
 
 	return total, nil
@@ -49,10 +63,9 @@ func fakeUsers(min, max int) []*User {
 	}
 
 	users := make([]*User, 0, max)
-	now := time.Now()
 
 	for i := min; i <= max; i++ {
-		users = append(users, &User{i, gimei.NewName().Kanji(), faker.Email(), 0, &now, &now})
+		users = append(users, &User{ID: i, Name: gimei.NewName().Kanji(), Email: faker.Email()})
 	}
 
 	return users
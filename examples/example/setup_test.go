@@ -27,6 +27,12 @@ func TestSetup(t *testing.T) {
 		"pgsql 1":                 {pgsqlDBType, pgsqlCfgPath, 1, 1, 1, 1},
 		"pgsql divisible chunk":   {pgsqlDBType, pgsqlCfgPath, 1, 100, 10, 100},
 		"pgsql indivisible chunk": {pgsqlDBType, pgsqlCfgPath, 1, 100, 11, 100},
+		// SQLite
+		"sqlite 1":                 {sqliteDBType, sqliteCfgPath, 1, 1, 1, 1},
+		"sqlite divisible chunk":   {sqliteDBType, sqliteCfgPath, 1, 100, 10, 100},
+		"sqlite indivisible chunk": {sqliteDBType, sqliteCfgPath, 1, 100, 11, 100},
+		// The chunk of the command exceeds the limit of bound variables of SQLite.
+		"sqlite large chunk": {sqliteDBType, sqliteCfgPath, 1, 10000, 10000, 10000},
 	}
 
 	for name, tt := range cases {
@@ -38,7 +44,7 @@ func TestSetup(t *testing.T) {
 			})
 
 			cfg := dbutil.NewConfigFile(cfgType, tt.path, cfgSection)
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 			t.Cleanup(cancel)
 
 			db, err := dbutil.NewDBContext(ctx, cfg)
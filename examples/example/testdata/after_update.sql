@@ -1,6 +1,6 @@
 INSERT INTO users (id, name, email, status, created_at, updated_at) VALUES
-(1, 'Alice', 'example1@example.com', 1, NOW(), NOW()),
-(2, 'Billy', 'example2@example.com', 0, NOW(), NOW()),
-(3, 'Chris', 'example3@example.com', 0, NOW(), NOW()),
-(4, 'Daisy', 'example4@example.com', 0, NOW(), NOW()),
-(5, 'Elise', 'example5@example.com', 0, NOW(), NOW());
+(1, 'Alice', 'example1@example.com', 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(2, 'Billy', 'example2@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(3, 'Chris', 'example3@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(4, 'Daisy', 'example4@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(5, 'Elise', 'example5@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
@@ -1,6 +1,6 @@
 INSERT INTO users (name, email, status, created_at, updated_at) VALUES
-('Alice', 'example1@example.com', 0, NOW(), NOW()),
-('Billy', 'example2@example.com', 0, NOW(), NOW()),
-('Chris', 'example3@example.com', 0, NOW(), NOW()),
-('Daisy', 'example4@example.com', 0, NOW(), NOW()),
-('Elise', 'example5@example.com', 0, NOW(), NOW());
+('Alice', 'example1@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+('Billy', 'example2@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+('Chris', 'example3@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+('Daisy', 'example4@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+('Elise', 'example5@example.com', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
@@ -0,0 +1,20 @@
+-- name: SelectUser
+-- Select a user by id and status.
+SELECT id, name, status, created_at, updated_at
+FROM users
+WHERE id = :id AND status = :status;
+
+-- name: UpdateStatus
+UPDATE users SET status = :afterSts, updated_at = CURRENT_TIMESTAMP WHERE id = :id AND status = :beforeSts;
+
+-- name: TruncateUsers
+-- dialect: mysql
+TRUNCATE TABLE users;
+
+-- name: TruncateUsers
+-- dialect: pgsql
+TRUNCATE TABLE users RESTART IDENTITY;
+
+-- name: TruncateUsers
+-- dialect: sqlite
+DELETE FROM users;